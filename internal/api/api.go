@@ -0,0 +1,229 @@
+// Package api serves the aggregator as a JSON REST API. the handlers only
+// talk to the database through the Store interface, so they can be tested
+// with httptest and a fake store.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johndosdos/blog_aggregator/internal/auth"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/rss"
+	"github.com/lib/pq"
+)
+
+// Store is the part of database.Queries the API uses
+type Store interface {
+	CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error)
+	CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error)
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error
+	GetFeedByUrlKey(ctx context.Context, urlKey string) (database.Feed, error)
+	GetFeedFollowsPageForUser(ctx context.Context, arg database.GetFeedFollowsPageForUserParams) ([]database.GetFeedFollowsPageForUserRow, error)
+	GetFeedsPage(ctx context.Context, arg database.GetFeedsPageParams) ([]database.GetFeedsPageRow, error)
+	GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.GetPostForUserRow, error)
+	GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error)
+	GetUserByApiKeyHash(ctx context.Context, apiKeyHash sql.NullString) (database.User, error)
+	GetUsersPage(ctx context.Context, arg database.GetUsersPageParams) ([]database.GetUsersPageRow, error)
+	MarkAllPostsRead(ctx context.Context, arg database.MarkAllPostsReadParams) (int64, error)
+	MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error
+	StarPost(ctx context.Context, arg database.StarPostParams) error
+	UnstarPost(ctx context.Context, arg database.UnstarPostParams) error
+}
+
+var _ Store = (*database.Queries)(nil)
+
+const (
+	// the page size when a request does not ask for one, and the largest page
+	// a request may ask for
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Server is the http.Handler of the API
+type Server struct {
+	store Store
+	mux   *http.ServeMux
+
+	// FetchFeed does the trial fetch of a feed before it is added. it only
+	// connects to public addresses, and is replaced in tests to keep them
+	// off the network
+	FetchFeed func(ctx context.Context, url string) (*rss.Feed, error)
+}
+
+// NewServer returns the API with every route registered
+func NewServer(store Store) *Server {
+	s := &Server{
+		store:     store,
+		mux:       http.NewServeMux(),
+		FetchFeed: rss.FetchPublicFeed,
+	}
+
+	// registering is the only request that needs no API key, it returns one
+	s.mux.HandleFunc("POST /users", s.handleCreateUser)
+	s.mux.HandleFunc("GET /users", s.requireUser(s.handleListUsers))
+	s.mux.HandleFunc("GET /users/me", s.requireUser(s.handleGetCurrentUser))
+
+	s.mux.HandleFunc("GET /feeds", s.requireUser(s.handleListFeeds))
+	s.mux.HandleFunc("POST /feeds", s.requireUser(s.handleCreateFeed))
+
+	s.mux.HandleFunc("GET /follows", s.requireUser(s.handleListFollows))
+	s.mux.HandleFunc("POST /follows", s.requireUser(s.handleCreateFollow))
+	s.mux.HandleFunc("DELETE /follows", s.requireUser(s.handleDeleteFollow))
+
+	s.mux.HandleFunc("GET /posts", s.requireUser(s.handleListPosts))
+	s.mux.HandleFunc("GET /posts/{id}", s.requireUser(s.handleGetPost))
+	s.mux.HandleFunc("PUT /posts/{id}/read", s.requireUser(s.handleMarkRead))
+	s.mux.HandleFunc("DELETE /posts/{id}/read", s.requireUser(s.handleMarkUnread))
+	s.mux.HandleFunc("PUT /posts/{id}/star", s.requireUser(s.handleStar))
+	s.mux.HandleFunc("DELETE /posts/{id}/star", s.requireUser(s.handleUnstar))
+	s.mux.HandleFunc("POST /posts/mark-all-read", s.requireUser(s.handleMarkAllRead))
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// userHandler is a handler that runs as the user making the request
+type userHandler func(w http.ResponseWriter, r *http.Request, user database.User)
+
+// requireUser looks up the user whose API key is the Bearer token of the
+// request and passes it to the handler, like MiddlewareLoggedIn does for the
+// commands
+func (s *Server) requireUser(handler userHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey, err := bearerToken(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		user, err := s.store.GetUserByApiKeyHash(r.Context(), sql.NullString{String: auth.HashAPIKey(apiKey), Valid: true})
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid API key."))
+			return
+		}
+		if err != nil {
+			writeInternalError(w, err, "failed to get user")
+			return
+		}
+
+		handler(w, r, user)
+	}
+}
+
+// bearerToken returns the token of the Authorization header of a request
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", fmt.Errorf("missing Authorization header, expected Bearer <api key>.")
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", fmt.Errorf("invalid Authorization header, expected Bearer <api key>.")
+	}
+
+	return token, nil
+}
+
+// page is a page of a list. Items is never null, so clients can always
+// iterate over it.
+type page[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// pagination returns the limit and offset query parameters of a request
+func pagination(r *http.Request) (limit, offset int, err error) {
+	limit, offset = defaultPageLimit, 0
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("invalid limit: %s, expected 1 to %d", value, maxPageLimit)
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: %s", value)
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// decodeJSON reads the JSON body of a request into v, rejecting unknown fields
+// so typos in a request don't go unnoticed
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// writeInternalError logs an unexpected error and answers with only the
+// message, the error may tell clients about the database or the server
+func writeInternalError(w http.ResponseWriter, err error, message string) {
+	log.Printf("%s: %v", message, err)
+	writeError(w, http.StatusInternalServerError, fmt.Errorf("%s.", message))
+}
+
+// writeStoreError writes the status code that matches an error from the
+// store: 404 for missing rows, 409 for duplicates and 500 for the rest
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	pqErr := &pq.Error{}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s: not found.", message))
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		writeError(w, http.StatusConflict, fmt.Errorf("%s: already exists.", message))
+	default:
+		writeInternalError(w, err, message)
+	}
+}
+
+// nullTime returns the time of a nullable column, nil when it is NULL
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
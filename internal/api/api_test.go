@@ -0,0 +1,469 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/auth"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/rss"
+	"github.com/lib/pq"
+)
+
+// fakeStore keeps users and feeds in memory and records the pages asked for.
+// the methods the tests do not need fail.
+type fakeStore struct {
+	users   []database.User
+	feeds   []database.Feed
+	follows []database.FeedFollow
+	posts   []database.GetPostForUserRow
+
+	usersPage database.GetUsersPageParams
+}
+
+var errNotImplemented = errors.New("not implemented by the fake store")
+
+func (f *fakeStore) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UrlKey:    arg.UrlKey,
+		UserID:    arg.UserID,
+	}
+	f.feeds = append(f.feeds, feed)
+	return feed, nil
+}
+
+func (f *fakeStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	for _, follow := range f.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, &pq.Error{Code: "23505"}
+		}
+	}
+	f.follows = append(f.follows, database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+	return database.CreateFeedFollowRow{ID: arg.ID, CreatedAt: arg.CreatedAt, UserID: arg.UserID, FeedID: arg.FeedID}, nil
+}
+
+func (f *fakeStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	for _, user := range f.users {
+		if user.Name == arg.Name {
+			return database.User{}, &pq.Error{Code: "23505"}
+		}
+	}
+	user := database.User{
+		ID:         arg.ID,
+		CreatedAt:  arg.CreatedAt,
+		UpdatedAt:  arg.UpdatedAt,
+		Name:       arg.Name,
+		ApiKeyHash: arg.ApiKeyHash,
+	}
+	f.users = append(f.users, user)
+	return user, nil
+}
+
+func (f *fakeStore) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	return errNotImplemented
+}
+
+func (f *fakeStore) GetFeedByUrlKey(ctx context.Context, urlKey string) (database.Feed, error) {
+	for _, feed := range f.feeds {
+		if feed.UrlKey == urlKey {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetFeedFollowsPageForUser(ctx context.Context, arg database.GetFeedFollowsPageForUserParams) ([]database.GetFeedFollowsPageForUserRow, error) {
+	return nil, errNotImplemented
+}
+
+func (f *fakeStore) GetFeedsPage(ctx context.Context, arg database.GetFeedsPageParams) ([]database.GetFeedsPageRow, error) {
+	return nil, errNotImplemented
+}
+
+func (f *fakeStore) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.GetPostForUserRow, error) {
+	for _, post := range f.posts {
+		if post.ID != arg.ID {
+			continue
+		}
+		for _, follow := range f.follows {
+			if follow.UserID == arg.UserID && follow.FeedID == post.FeedID {
+				return post, nil
+			}
+		}
+	}
+	return database.GetPostForUserRow{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	return nil, errNotImplemented
+}
+
+func (f *fakeStore) GetUserByApiKeyHash(ctx context.Context, apiKeyHash sql.NullString) (database.User, error) {
+	for _, user := range f.users {
+		if user.ApiKeyHash == apiKeyHash {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUsersPage(ctx context.Context, arg database.GetUsersPageParams) ([]database.GetUsersPageRow, error) {
+	f.usersPage = arg
+
+	rows := []database.GetUsersPageRow{}
+	start := min(int(arg.PageOffset), len(f.users))
+	end := min(start+int(arg.PageLimit), len(f.users))
+	for _, user := range f.users[start:end] {
+		rows = append(rows, database.GetUsersPageRow{ID: user.ID, CreatedAt: user.CreatedAt, Name: user.Name})
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) MarkAllPostsRead(ctx context.Context, arg database.MarkAllPostsReadParams) (int64, error) {
+	return 0, errNotImplemented
+}
+
+func (f *fakeStore) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	return nil
+}
+
+func (f *fakeStore) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	return nil
+}
+
+func (f *fakeStore) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	return nil
+}
+
+func (f *fakeStore) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	return nil
+}
+
+// addUser adds a user to the store and returns their API key
+func (f *fakeStore) addUser(name string) (database.User, string) {
+	apiKey, err := auth.NewAPIKey()
+	if err != nil {
+		panic(err)
+	}
+	user := database.User{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		Name:       name,
+		ApiKeyHash: sql.NullString{String: auth.HashAPIKey(apiKey), Valid: true},
+	}
+	f.users = append(f.users, user)
+	return user, apiKey
+}
+
+// newTestServer returns a server on the fake store whose trial fetches
+// return a feed titled Example, without touching the network
+func newTestServer(store *fakeStore) *Server {
+	server := NewServer(store)
+	server.FetchFeed = func(ctx context.Context, url string) (*rss.Feed, error) {
+		return &rss.Feed{Title: "Example"}, nil
+	}
+	return server
+}
+
+// do sends a request to the server and returns the response, with the
+// Bearer token set when apiKey is not empty
+func do(t *testing.T, server http.Handler, method, target, body, apiKey string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	res := httptest.NewRecorder()
+	server.ServeHTTP(res, req)
+	return res
+}
+
+func TestRequireUser(t *testing.T) {
+	store := &fakeStore{}
+	user, apiKey := store.addUser("alice")
+	server := newTestServer(store)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + apiKey, http.StatusUnauthorized},
+		{"missing token", "Bearer ", http.StatusUnauthorized},
+		{"unknown key", "Bearer gator_unknown", http.StatusUnauthorized},
+		{"valid key", "Bearer " + apiKey, http.StatusOK},
+		{"lowercase scheme", "bearer " + apiKey, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			if res.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", res.Code, test.status, res.Body)
+			}
+			if test.status == http.StatusUnauthorized && res.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+			if test.status == http.StatusOK {
+				got := userResponse{}
+				if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got.ID != user.ID || got.Name != user.Name {
+					t.Errorf("user = %+v, want %s", got, user.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	store := &fakeStore{}
+	store.addUser("alice")
+	server := newTestServer(store)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"created", `{"name": "bob"}`, http.StatusCreated},
+		{"duplicate name", `{"name": "alice"}`, http.StatusConflict},
+		{"missing name", `{"name": "  "}`, http.StatusBadRequest},
+		{"unknown field", `{"name": "carol", "admin": true}`, http.StatusBadRequest},
+		{"invalid JSON", `{"name":`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := do(t, server, http.MethodPost, "/users", test.body, "")
+			if res.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", res.Code, test.status, res.Body)
+			}
+			if res.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", res.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	// the returned key authenticates the new user
+	res := do(t, server, http.MethodPost, "/users", `{"name": "dave"}`, "")
+	created := createUserResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	res = do(t, server, http.MethodGet, "/users/me", "", created.APIKey)
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"dave"`) {
+		t.Errorf("GET /users/me with the new key = %d %s, want dave", res.Code, res.Body)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	store := &fakeStore{}
+	_, apiKey := store.addUser("alice")
+	for _, name := range []string{"bob", "carol", "dave", "erin"} {
+		store.addUser(name)
+	}
+	server := newTestServer(store)
+
+	tests := []struct {
+		query  string
+		status int
+		limit  int32
+		offset int32
+		names  []string
+	}{
+		{"", http.StatusOK, defaultPageLimit, 0, []string{"alice", "bob", "carol", "dave", "erin"}},
+		{"?limit=2", http.StatusOK, 2, 0, []string{"alice", "bob"}},
+		{"?limit=2&offset=2", http.StatusOK, 2, 2, []string{"carol", "dave"}},
+		{"?limit=2&offset=4", http.StatusOK, 2, 4, []string{"erin"}},
+		{"?offset=10", http.StatusOK, defaultPageLimit, 10, []string{}},
+		{"?limit=100", http.StatusOK, 100, 0, []string{"alice", "bob", "carol", "dave", "erin"}},
+		{"?limit=0", http.StatusBadRequest, 0, 0, nil},
+		{"?limit=101", http.StatusBadRequest, 0, 0, nil},
+		{"?limit=ten", http.StatusBadRequest, 0, 0, nil},
+		{"?offset=-1", http.StatusBadRequest, 0, 0, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			store.usersPage = database.GetUsersPageParams{}
+			res := do(t, server, http.MethodGet, "/users"+test.query, "", apiKey)
+			if res.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", res.Code, test.status, res.Body)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			// the page is asked from the store, not cut from the whole table
+			if store.usersPage.PageLimit != test.limit || store.usersPage.PageOffset != test.offset {
+				t.Errorf("store page = %+v, want limit %d offset %d", store.usersPage, test.limit, test.offset)
+			}
+
+			got := page[userResponse]{}
+			if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Items == nil {
+				t.Fatalf("items = null, want a list")
+			}
+			names := []string{}
+			for _, user := range got.Items {
+				names = append(names, user.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(test.names, ",") {
+				t.Errorf("users = %v, want %v", names, test.names)
+			}
+			if got.Limit != int(test.limit) || got.Offset != int(test.offset) {
+				t.Errorf("page = limit %d offset %d, want limit %d offset %d", got.Limit, got.Offset, test.limit, test.offset)
+			}
+		})
+	}
+}
+
+func TestCreateFeed(t *testing.T) {
+	store := &fakeStore{}
+	_, apiKey := store.addUser("alice")
+	server := newTestServer(store)
+
+	res := do(t, server, http.MethodPost, "/feeds", `{"url": "https://Example.com/feed/"}`, apiKey)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", res.Code, http.StatusCreated, res.Body)
+	}
+	got := feedResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	// the name defaults to the title, and the URL is kept as it was fetched
+	if got.Name != "Example" || got.URL != "https://Example.com/feed/" || got.User != "alice" {
+		t.Errorf("feed = %+v", got)
+	}
+	if len(store.follows) != 1 || store.follows[0].FeedID != got.ID {
+		t.Errorf("follows = %+v, want a follow of the new feed", store.follows)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"same feed over http", `{"url": "http://example.com/feed"}`, http.StatusConflict},
+		{"not http", `{"url": "ftp://example.com/feed"}`, http.StatusBadRequest},
+		{"relative URL", `{"url": "/feed"}`, http.StatusBadRequest},
+		{"missing body", ``, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := do(t, server, http.MethodPost, "/feeds", test.body, apiKey)
+			if res.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", res.Code, test.status, res.Body)
+			}
+		})
+	}
+
+	t.Run("fetch fails", func(t *testing.T) {
+		server.FetchFeed = func(ctx context.Context, url string) (*rss.Feed, error) {
+			return nil, &rss.HTTPError{URL: url, StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+		}
+		res := do(t, server, http.MethodPost, "/feeds", `{"url": "https://example.org/feed"}`, apiKey)
+		if res.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want %d: %s", res.Code, http.StatusUnprocessableEntity, res.Body)
+		}
+	})
+}
+
+func TestGetPost(t *testing.T) {
+	store := &fakeStore{}
+	user, apiKey := store.addUser("alice")
+	post := database.GetPostForUserRow{ID: uuid.New(), Title: "Hello", Url: "https://example.com/hello", FeedID: uuid.New()}
+	store.follows = append(store.follows, database.FeedFollow{ID: uuid.New(), UserID: user.ID, FeedID: post.FeedID})
+	// a post of a feed alice does not follow
+	other := database.GetPostForUserRow{ID: uuid.New(), Title: "Other", Url: "https://example.org/other", FeedID: uuid.New()}
+	store.posts = append(store.posts, post, other)
+	server := newTestServer(store)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"get", http.MethodGet, "/posts/" + post.ID.String(), http.StatusOK},
+		{"mark read", http.MethodPut, "/posts/" + post.ID.String() + "/read", http.StatusNoContent},
+		{"unstar", http.MethodDelete, "/posts/" + post.ID.String() + "/star", http.StatusNoContent},
+		{"invalid ID", http.MethodGet, "/posts/42", http.StatusBadRequest},
+		{"missing post", http.MethodGet, "/posts/" + uuid.NewString(), http.StatusNotFound},
+		{"missing post star", http.MethodPut, "/posts/" + uuid.NewString() + "/star", http.StatusNotFound},
+		{"unfollowed post", http.MethodGet, "/posts/" + other.ID.String(), http.StatusNotFound},
+		{"unfollowed post read", http.MethodPut, "/posts/" + other.ID.String() + "/read", http.StatusNotFound},
+		{"unfollowed post star", http.MethodPut, "/posts/" + other.ID.String() + "/star", http.StatusNotFound},
+		{"wrong method", http.MethodPost, "/posts/" + post.ID.String(), http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := do(t, server, test.method, test.target, "", apiKey)
+			if res.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", res.Code, test.status, res.Body)
+			}
+		})
+	}
+}
+
+func TestInternalError(t *testing.T) {
+	store := &fakeStore{}
+	_, apiKey := store.addUser("alice")
+	server := newTestServer(store)
+
+	// the error is logged on the server, not sent to the client
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	res := do(t, server, http.MethodPost, "/posts/mark-all-read", "{}", apiKey)
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d: %s", res.Code, http.StatusInternalServerError, res.Body)
+	}
+
+	got := errorResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Error != "failed to mark posts as read." {
+		t.Errorf("error = %q, want a generic message", got.Error)
+	}
+	if !strings.Contains(logged.String(), errNotImplemented.Error()) {
+		t.Errorf("log = %q, want the store error", logged.String())
+	}
+}
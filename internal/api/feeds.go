@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/canonical"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/rss"
+)
+
+// feedResponse is a feed as returned by the API
+type feedResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	User          string     `json:"user"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+// followResponse is a followed feed as returned by the API
+type followResponse struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	Feed       string    `json:"feed"`
+	URL        string    `json:"url"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// GET /feeds
+func (s *Server) handleListFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	feeds, err := s.store.GetFeedsPage(r.Context(), database.GetFeedsPageParams{
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to get feeds")
+		return
+	}
+
+	responses := make([]feedResponse, 0, len(feeds))
+	for _, feed := range feeds {
+		responses = append(responses, feedResponse{
+			ID:            feed.ID,
+			Name:          feed.Name,
+			URL:           feed.Url,
+			User:          feed.Username,
+			CreatedAt:     feed.CreatedAt,
+			LastFetchedAt: nullTime(feed.LastFetchedAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, page[feedResponse]{Items: responses, Limit: limit, Offset: offset})
+}
+
+// POST /feeds {"name": "...", "url": "..."}
+//
+// adds a feed and follows it. like the addfeed command, the feed is fetched
+// first so only working feeds are added, and the name defaults to its title.
+func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	body := struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}{}
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	parsedURL, err := url.Parse(body.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid feed URL, expected an http(s) URL: %s", body.URL))
+		return
+	}
+	feedKey, err := canonical.Key(body.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	_, err = s.store.GetFeedByUrlKey(r.Context(), feedKey)
+	if err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("feed already exists: %s, follow it instead.", body.URL))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeStoreError(w, err, "failed to get feed")
+		return
+	}
+
+	fetchCtx, cancel := context.WithTimeout(r.Context(), rss.FetchTimeout)
+	defer cancel()
+	fetchedFeed, err := s.FetchFeed(fetchCtx, body.URL)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("failed to fetch feed: %w", err))
+		return
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		name = strings.TrimSpace(fetchedFeed.Title)
+	}
+	if name == "" {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("the feed has no title, add it again with a name."))
+		return
+	}
+
+	feed, err := s.store.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      name,
+		Url:       body.URL,
+		UrlKey:    feedKey,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to create feed")
+		return
+	}
+
+	_, err = s.store.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to follow feed")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, feedResponse{
+		ID:        feed.ID,
+		Name:      feed.Name,
+		URL:       feed.Url,
+		User:      user.Name,
+		CreatedAt: feed.CreatedAt,
+	})
+}
+
+// GET /follows
+func (s *Server) handleListFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	follows, err := s.store.GetFeedFollowsPageForUser(r.Context(), database.GetFeedFollowsPageForUserParams{
+		UserID:     user.ID,
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to get followed feeds")
+		return
+	}
+
+	responses := make([]followResponse, 0, len(follows))
+	for _, follow := range follows {
+		responses = append(responses, followResponse{
+			FeedID:     follow.FeedID,
+			Feed:       follow.FeedName,
+			URL:        follow.Url,
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, page[followResponse]{Items: responses, Limit: limit, Offset: offset})
+}
+
+// POST /follows {"url": "..."}
+func (s *Server) handleCreateFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	body := struct {
+		URL string `json:"url"`
+	}{}
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	feedKey, err := canonical.Key(body.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	feed, err := s.store.GetFeedByUrlKey(r.Context(), feedKey)
+	if err != nil {
+		writeStoreError(w, err, "feed "+body.URL)
+		return
+	}
+
+	follow, err := s.store.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		writeStoreError(w, err, "follow of "+feed.Url)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, followResponse{
+		FeedID:     feed.ID,
+		Feed:       feed.Name,
+		URL:        feed.Url,
+		FollowedAt: follow.CreatedAt,
+	})
+}
+
+// DELETE /follows?url=...
+func (s *Server) handleDeleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedKey, err := canonical.Key(r.URL.Query().Get("url"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.store.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: user.ID,
+		UrlKey: feedKey,
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to unfollow feed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+)
+
+// postResponse is a post as returned by the API. Read and Starred are only
+// set in the posts listed for a user.
+type postResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	Feed        string     `json:"feed,omitempty"`
+	Read        *bool      `json:"read,omitempty"`
+	Starred     *bool      `json:"starred,omitempty"`
+}
+
+// GET /posts?limit=&offset=&unread=true&starred=true&feed_id=
+func (s *Server) handleListPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := database.GetPostsForUserParams{
+		UserID:     user.ID,
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	}
+
+	query := r.URL.Query()
+	for name, filter := range map[string]*bool{"unread": &params.UnreadOnly, "starred": &params.StarredOnly} {
+		if value := query.Get(name); value != "" {
+			*filter, err = strconv.ParseBool(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %s, expected true or false", name, value))
+				return
+			}
+		}
+	}
+	if value := query.Get("feed_id"); value != "" {
+		feedID, err := uuid.Parse(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid feed_id: %s", value))
+			return
+		}
+		params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+
+	posts, err := s.store.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		writeStoreError(w, err, "failed to get posts")
+		return
+	}
+
+	responses := make([]postResponse, 0, len(posts))
+	for _, post := range posts {
+		read, starred := post.ReadAt.Valid, post.StarredAt.Valid
+		responses = append(responses, postResponse{
+			ID:          post.ID,
+			Title:       post.Title,
+			URL:         post.Url,
+			Description: post.Description.String,
+			PublishedAt: nullTime(post.PublishedAt),
+			FeedID:      post.FeedID,
+			Feed:        post.FeedName,
+			Read:        &read,
+			Starred:     &starred,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, page[postResponse]{Items: responses, Limit: limit, Offset: offset})
+}
+
+// GET /posts/{id}
+func (s *Server) handleGetPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := s.getPost(w, r, user)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, postResponse{
+		ID:          post.ID,
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: post.Description.String,
+		PublishedAt: nullTime(post.PublishedAt),
+		FeedID:      post.FeedID,
+	})
+}
+
+// PUT /posts/{id}/read
+func (s *Server) handleMarkRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := s.getPost(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := s.store.MarkPostRead(r.Context(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to mark post as read")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /posts/{id}/read
+func (s *Server) handleMarkUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := s.getPost(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := s.store.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to mark post as unread")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /posts/{id}/star
+func (s *Server) handleStar(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := s.getPost(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := s.store.StarPost(r.Context(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		StarredAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to star post")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /posts/{id}/star
+func (s *Server) handleUnstar(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := s.getPost(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := s.store.UnstarPost(r.Context(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to unstar post")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /posts/mark-all-read {"feed_id": "...", "before": "2024-06-01T00:00:00Z"}
+//
+// both fields are optional, like the flags of the mark-all-read command
+func (s *Server) handleMarkAllRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	body := struct {
+		FeedID *uuid.UUID `json:"feed_id"`
+		Before *time.Time `json:"before"`
+	}{}
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := database.MarkAllPostsReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: user.ID,
+	}
+	if body.FeedID != nil {
+		params.FeedID = uuid.NullUUID{UUID: *body.FeedID, Valid: true}
+	}
+	if body.Before != nil {
+		params.Before = sql.NullTime{Time: body.Before.UTC(), Valid: true}
+	}
+
+	count, err := s.store.MarkAllPostsRead(r.Context(), params)
+	if err != nil {
+		writeStoreError(w, err, "failed to mark posts as read")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Marked int64 `json:"marked"`
+	}{Marked: count})
+}
+
+// getPost returns the post named by the {id} of the route, or writes the
+// error response. the posts of feeds the user does not follow are not found,
+// like in the post list.
+func (s *Server) getPost(w http.ResponseWriter, r *http.Request, user database.User) (database.GetPostForUserRow, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid post ID: %s", r.PathValue("id")))
+		return database.GetPostForUserRow{}, false
+	}
+
+	post, err := s.store.GetPostForUser(r.Context(), database.GetPostForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		writeStoreError(w, err, "post "+id.String())
+		return database.GetPostForUserRow{}, false
+	}
+
+	return post, true
+}
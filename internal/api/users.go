@@ -0,0 +1,109 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/auth"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+)
+
+// userResponse is a user as returned by the API
+type userResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newUserResponse(user database.User) userResponse {
+	return userResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// createUserResponse is the new user and their API key. only the hash of the
+// key is stored, so this is the only response that contains it.
+type createUserResponse struct {
+	userResponse
+	APIKey string `json:"api_key"`
+}
+
+// GET /users
+func (s *Server) handleListUsers(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	users, err := s.store.GetUsersPage(r.Context(), database.GetUsersPageParams{
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		writeStoreError(w, err, "failed to get users")
+		return
+	}
+
+	responses := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, userResponse{
+			ID:        user.ID,
+			Name:      user.Name,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, page[userResponse]{Items: responses, Limit: limit, Offset: offset})
+}
+
+// POST /users {"name": "..."}
+func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Name string `json:"name"`
+	}{}
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("name is required."))
+		return
+	}
+
+	apiKey, err := auth.NewAPIKey()
+	if err != nil {
+		writeInternalError(w, err, "failed to create API key")
+		return
+	}
+
+	user, err := s.store.CreateUser(r.Context(), database.CreateUserParams{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		Name:       name,
+		ApiKeyHash: sql.NullString{String: auth.HashAPIKey(apiKey), Valid: true},
+	})
+	if err != nil {
+		writeStoreError(w, err, "user "+name)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createUserResponse{
+		userResponse: newUserResponse(user),
+		APIKey:       apiKey,
+	})
+}
+
+// GET /users/me
+func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, newUserResponse(user))
+}
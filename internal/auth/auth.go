@@ -0,0 +1,35 @@
+// Package auth creates the API keys of the users. only the hash of a key is
+// stored, the key itself is shown to the user once.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+// keyPrefix makes the keys easy to recognise, e.g. in a leaked config file
+const keyPrefix = "gator_"
+
+// NewAPIKey returns a new random API key
+func NewAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hash of an API key as it is stored in the database.
+// the keys are random and long, so a fast unsalted hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKey reports whether key matches the stored hash
+func CheckAPIKey(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,112 @@
+package canonical
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters added by analytics and ad platforms.
+// they do not change the document, so they are dropped. every parameter
+// starting with utm_ is dropped as well.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"igshid":  true,
+	"yclid":   true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+// URL returns the canonical form of an absolute URL, so that URLs pointing
+// at the same document compare equal. the scheme and host are lowercased,
+// default ports, fragments, trailing slashes and tracking parameters are
+// dropped, and the query parameters are sorted. the rest of the URL is kept
+// as it is written, without escaping or unescaping it.
+//
+// the canonical_url function in sql/schema/009_canonical_urls.sql mirrors
+// this function for the rows stored before it existed, and must give the
+// same result for every URL.
+func URL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid URL, expected an absolute URL: %s", rawURL)
+	}
+
+	// the URL is cut into its raw parts like the SQL function does, since
+	// u.String() would escape spaces and non-ASCII characters in the path
+	scheme, rest, _ := strings.Cut(rawURL, "://")
+	scheme = strings.ToLower(scheme)
+	rest, _, _ = strings.Cut(rest, "#")
+
+	authorityEnd := strings.IndexAny(rest, "/?")
+	if authorityEnd < 0 {
+		authorityEnd = len(rest)
+	}
+	authority, rest := rest[:authorityEnd], rest[authorityEnd:]
+	path, query, _ := strings.Cut(rest, "?")
+
+	// the user info is kept as it is, a password is case sensitive
+	userinfo, host := "", authority
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		userinfo, host = authority[:i+1], authority[i+1:]
+	}
+	host = strings.ToLower(host)
+	if scheme == "http" {
+		host = strings.TrimSuffix(host, ":80")
+	} else if scheme == "https" {
+		host = strings.TrimSuffix(host, ":443")
+	}
+
+	canonicalURL := scheme + "://" + userinfo + host + strings.TrimRight(path, "/")
+	if query := canonicalQuery(query); query != "" {
+		canonicalURL += "?" + query
+	}
+
+	return canonicalURL, nil
+}
+
+// Key returns the canonical URL without its scheme. feeds and posts are
+// identified by their key, so the http and https URLs of a feed are the
+// same feed.
+func Key(rawURL string) (string, error) {
+	canonicalURL, err := URL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	_, key, _ := strings.Cut(canonicalURL, "://")
+	return key, nil
+}
+
+// canonicalQuery drops the empty and tracking parameters of a raw query and
+// sorts the rest. the parameters are kept as they are, without decoding, so
+// the result matches the canonical_url SQL function.
+func canonicalQuery(rawQuery string) string {
+	params := []string{}
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(param, "=")
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "utm_") || trackingParams[name] {
+			continue
+		}
+
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/canonical"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/rss"
+)
+
+// scrapeOptions controls how many feeds are fetched per tick, how long a
+// single fetch may take and how many failures in a row disable a feed
+type scrapeOptions struct {
+	workers     int
+	batchSize   int
+	feedTimeout time.Duration
+	maxFailures int
+}
+
+// lease returns how long the feeds of a batch stay claimed. the workers
+// fetch the batch in rounds that each take up to feedTimeout, and saving the
+// posts of a feed gets some time on top.
+func (opts scrapeOptions) lease() time.Duration {
+	rounds := (opts.batchSize + opts.workers - 1) / opts.workers
+	return time.Duration(rounds)*opts.feedTimeout + claimLeaseMargin
+}
+
+const (
+	// the time a claimed feed is given to save its posts after the fetch
+	claimLeaseMargin = time.Minute
+
+	// the delay before retrying a failed feed doubles with every failure in
+	// a row, from retryBaseDelay up to retryMaxDelay
+	retryBaseDelay = 5 * time.Minute
+	retryMaxDelay  = 24 * time.Hour
+)
+
+func HandlerAgg(s *State, cmd Command) error {
+	// the agg command accepts one argument, the time between requests. e.g. 1m, 30s
+	// e.g. agg 1m --workers 8
+	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
+	if err != nil {
+		return usageErrorf("invalid time between requests: %w", err)
+	}
+	if timeBetweenRequests <= 0 {
+		return usageErrorf("time between requests must be positive: %s", timeBetweenRequests)
+	}
+
+	opts := scrapeOptions{
+		workers:     cmd.Int("workers"),
+		batchSize:   cmd.Int("batch"),
+		feedTimeout: cmd.Duration("timeout"),
+		maxFailures: cmd.Int("max-failures"),
+	}
+	if opts.workers <= 0 {
+		return usageErrorf("number of workers must be positive: %d", opts.workers)
+	}
+	if opts.batchSize <= 0 {
+		return usageErrorf("batch size must be positive: %d", opts.batchSize)
+	}
+	if opts.feedTimeout <= 0 {
+		return usageErrorf("feed timeout must be positive: %s", opts.feedTimeout)
+	}
+	if opts.maxFailures <= 0 {
+		return usageErrorf("max failures must be positive: %d", opts.maxFailures)
+	}
+
+	// stop the loop on ctrl-c or when the process is asked to terminate
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("collecting feeds every %s with %d workers\n", timeBetweenRequests, opts.workers)
+
+	ticker := time.NewTicker(timeBetweenRequests)
+	defer ticker.Stop()
+
+	// scrape once right away, then on every tick. ticks that fire while a
+	// batch is still running are coalesced by the ticker, so batches never overlap.
+	for {
+		if err := scrapeFeeds(ctx, s, opts); err != nil {
+			fmt.Println(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("aggregator has been stopped.")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrapeFeeds claims the feeds that have gone the longest without being
+// fetched and fetches them in parallel. feeds that have never been fetched
+// come first.
+func scrapeFeeds(ctx context.Context, s *State, opts scrapeOptions) error {
+	// the row locks of the claim only last for the statement, so it also
+	// leases the feeds by moving their next attempt past the time the batch
+	// can take. other aggregator processes skip them until the lease ends,
+	// and the fetch replaces it with the real next attempt. last_fetched_at
+	// is only set once a fetch succeeds.
+	now := time.Now().UTC()
+	feeds, err := s.DB.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		UpdatedAt:     now,
+		Limit:         int32(opts.batchSize),
+		NextAttemptAt: sql.NullTime{Time: now.Add(opts.lease()), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to claim feeds: %w", err)
+	}
+	if len(feeds) == 0 {
+		return fmt.Errorf("no feeds to fetch.")
+	}
+
+	jobs := make(chan database.Feed)
+	wg := sync.WaitGroup{}
+	for range min(opts.workers, len(feeds)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				if err := scrapeFeed(ctx, s, feed, opts); err != nil {
+					fmt.Println(err)
+				}
+			}
+		}()
+	}
+
+sendJobs:
+	for _, feed := range feeds {
+		select {
+		case jobs <- feed:
+		case <-ctx.Done():
+			break sendJobs
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return nil
+}
+
+// scrapeFeed fetches a single feed and saves its items as posts
+func scrapeFeed(ctx context.Context, s *State, dbFeed database.Feed, opts scrapeOptions) error {
+	fetchCtx, cancel := context.WithTimeout(ctx, opts.feedTimeout)
+	defer cancel()
+
+	// send the validators of the previous fetch so unchanged feeds are not
+	// downloaded again
+	result, err := rss.FetchFeedConditional(fetchCtx, dbFeed.Url, rss.CacheValidators{
+		ETag:         dbFeed.Etag.String,
+		LastModified: dbFeed.LastModified.String,
+	})
+	if err != nil {
+		// a fetch cut short by shutdown says nothing about the feed
+		if ctx.Err() == nil {
+			if markErr := markFeedFailed(ctx, s, dbFeed, err, opts.maxFailures); markErr != nil {
+				fmt.Println(markErr)
+			}
+		}
+		return fmt.Errorf("failed to fetch feed %s: %w", dbFeed.Url, err)
+	}
+
+	// a 304 has no body to read the schedule from, so keep the stored one
+	schedule := rss.Schedule{
+		MinInterval: time.Duration(dbFeed.MinRefreshInterval) * time.Second,
+		SkipHours:   uint32(dbFeed.SkipHours),
+		SkipDays:    uint8(dbFeed.SkipDays),
+	}
+	if !result.NotModified {
+		schedule = result.Feed.Schedule
+		err = s.DB.SetFeedRefreshSchedule(ctx, database.SetFeedRefreshScheduleParams{
+			ID:                 dbFeed.ID,
+			MinRefreshInterval: int32(schedule.MinInterval / time.Second),
+			SkipHours:          int32(schedule.SkipHours),
+			SkipDays:           int32(schedule.SkipDays),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save refresh schedule for %s: %w", dbFeed.Url, err)
+		}
+	}
+
+	// the feed is not fetched again before the publisher allows it
+	now := time.Now().UTC()
+	err = s.DB.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+		ID:            dbFeed.ID,
+		LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+		NextAttemptAt: sql.NullTime{Time: schedule.NextFetch(now), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark feed %s as fetched: %w", dbFeed.Url, err)
+	}
+
+	err = s.DB.SetFeedCacheValidators(ctx, database.SetFeedCacheValidatorsParams{
+		ID:           dbFeed.ID,
+		Etag:         sql.NullString{String: result.Validators.ETag, Valid: result.Validators.ETag != ""},
+		LastModified: sql.NullString{String: result.Validators.LastModified, Valid: result.Validators.LastModified != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save cache validators for %s: %w", dbFeed.Url, err)
+	}
+
+	if result.NotModified {
+		fmt.Printf("%s: not modified\n", dbFeed.Name)
+		return nil
+	}
+	feed := result.Feed
+
+	savedPosts := 0
+	for _, item := range feed.Items {
+		// posts are keyed by their canonical link, so items without a link
+		// cannot be stored. links that cannot be canonicalised are their own
+		// key.
+		if item.Link == "" {
+			continue
+		}
+		postKey, err := canonical.Key(item.Link)
+		if err != nil {
+			postKey = item.Link
+		}
+
+		// posts with an unknown date format are kept without a date
+		publishedAt := sql.NullTime{Time: item.Published, Valid: !item.Published.IsZero()}
+
+		err = s.DB.UpsertPost(ctx, database.UpsertPostParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
+			Title:       item.Title,
+			Url:         item.Link,
+			UrlKey:      postKey,
+			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
+			PublishedAt: publishedAt,
+			FeedID:      dbFeed.ID,
+		})
+		if err != nil {
+			fmt.Printf("failed to save post %s: %v\n", item.Link, err)
+			continue
+		}
+		savedPosts++
+	}
+
+	fmt.Printf("%s: %d of %d posts saved\n", dbFeed.Name, savedPosts, len(feed.Items))
+
+	return nil
+}
+
+// markFeedFailed records a failed fetch and schedules the next attempt with
+// exponential backoff, or after Retry-After when it is longer. the feed is
+// disabled after maxFailures failures in a row, or right away when the
+// server answers with a status that a retry will not change, like 404.
+func markFeedFailed(ctx context.Context, s *State, dbFeed database.Feed, fetchErr error, maxFailures int) error {
+	now := time.Now().UTC()
+	failures := dbFeed.ConsecutiveFailures + 1
+
+	// only 410 Gone says the feed will never come back. other errors,
+	// including the other 4xx, are retried until maxFailures in a row.
+	httpErr := &rss.HTTPError{}
+	gone := errors.As(fetchErr, &httpErr) && httpErr.StatusCode == http.StatusGone
+
+	disabledAt := sql.NullTime{}
+	switch {
+	case gone:
+		disabledAt = sql.NullTime{Time: now, Valid: true}
+		fmt.Printf("%s: disabled, the server answered %s\n", dbFeed.Name, httpErr.Status)
+	case int(failures) >= maxFailures:
+		disabledAt = sql.NullTime{Time: now, Valid: true}
+		fmt.Printf("%s: disabled after %d failed fetches in a row\n", dbFeed.Name, failures)
+	}
+
+	// never retry sooner than a 429 or 503 response asked for
+	delay := retryDelay(int(failures))
+	if httpErr.RetryAfter > delay {
+		delay = httpErr.RetryAfter
+	}
+
+	err := s.DB.MarkFeedFailed(ctx, database.MarkFeedFailedParams{
+		ID:                  dbFeed.ID,
+		LastError:           sql.NullString{String: fetchErr.Error(), Valid: true},
+		ConsecutiveFailures: failures,
+		NextAttemptAt:       sql.NullTime{Time: now.Add(delay), Valid: true},
+		DisabledAt:          disabledAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record error for feed %s: %w", dbFeed.Url, err)
+	}
+
+	return nil
+}
+
+// retryDelay returns the backoff after the given number of failures in a
+// row. the delay is picked at random from the upper half of the backoff so
+// feeds that failed together are not retried together.
+func retryDelay(failures int) time.Duration {
+	delay := retryMaxDelay
+	if shift := failures - 1; shift < 16 {
+		delay = min(retryBaseDelay<<shift, retryMaxDelay)
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
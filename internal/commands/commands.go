@@ -3,10 +3,18 @@ package commands
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/auth"
+	"github.com/johndosdos/blog_aggregator/internal/canonical"
 	"github.com/johndosdos/blog_aggregator/internal/config"
 	"github.com/johndosdos/blog_aggregator/internal/database"
 	"github.com/johndosdos/blog_aggregator/internal/rss"
@@ -16,26 +24,59 @@ type State struct {
 	// store the state for each user
 	Config *config.Config
 	DB     *database.Queries
+	// the connection behind DB, used to begin transactions
+	DBConn *sql.DB
 }
 
 type Command struct {
 	// e.g. gator <command name> [arguments]. store them here
 	Name string
 	Args []string
+	// the parsed flags of the command. the registry moves the flags out of
+	// Args, so Args only holds the positional arguments.
+	Flags *flag.FlagSet
 }
 
 type Commands struct {
 	// a handler is a function handling the <command name> argument
 	Handlers map[string]func(*State, Command) error
+	// the definition of every registered command, used for help and usage
+	Definitions map[string]Definition
 }
 
-func (c *Commands) Register(name string, f func(*State, Command) error) {
-	c.Handlers[name] = f
+// Register adds a command to the registry. the handler parses the flags and
+// checks the number of arguments first, and logs in the current user when the
+// command needs it. --help prints the help of the command instead.
+func (c *Commands) Register(def Definition) {
+	handler := def.Handler
+	if def.NeedsLogin() {
+		handler = MiddlewareLoggedIn(def.LoggedInHandler)
+	}
+
+	c.Definitions[def.Name] = def
+	c.Handlers[def.Name] = func(s *State, cmd Command) error {
+		parsed, err := def.parse(cmd)
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandHelp(def)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// usage errors from the handler get the usage of the command
+		err = handler(s, parsed)
+		usageErr := &UsageError{}
+		if errors.As(err, &usageErr) && usageErr.Usage == "" {
+			usageErr.Usage = def.Usage
+		}
+		return err
+	}
 }
 
 func (c *Commands) Run(s *State, cmd Command) error {
 	if handler, ok := c.Handlers[cmd.Name]; !ok {
-		return fmt.Errorf("unable to find command.")
+		return c.unknownCommandError(cmd.Name)
 	} else {
 		return handler(s, cmd)
 	}
@@ -48,22 +89,29 @@ func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User)
 			return fmt.Errorf("failed to get user: %w", err)
 		}
 
+		// users with an API key must have it in the config, so nobody can act
+		// as them by editing the user name
+		if user.ApiKeyHash.Valid {
+			if s.Config.APIKey == "" {
+				return fmt.Errorf("%s has an API key, log in with: gator login %s --key <api key>", user.Name, user.Name)
+			}
+			if !auth.CheckAPIKey(s.Config.APIKey, user.ApiKeyHash.String) {
+				return fmt.Errorf("the API key in the config does not belong to %s, log in again.", user.Name)
+			}
+		}
+
 		return handler(s, cmd, user)
 	}
 }
 
 func HandlerLogin(s *State, cmd Command) error {
 	// cmd.Args is the username
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("username is required for login.")
-	}
-
 	username := cmd.Args[0]
 	if username == "" {
 		return fmt.Errorf("invalid username provided: %s", s.Config.CurrentUserName)
 	}
 
-	_, err := s.DB.GetUser(context.Background(), username)
+	user, err := s.DB.GetUser(context.Background(), username)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("user not found, login failed. %w", err)
@@ -71,39 +119,64 @@ func HandlerLogin(s *State, cmd Command) error {
 		return err
 	}
 
+	// users with an API key log in with it, users created before API keys
+	// existed still log in with their name alone
+	apiKey := cmd.String("key")
+	if user.ApiKeyHash.Valid {
+		if apiKey == "" {
+			return usageErrorf("%s has an API key, log in with --key <api key>.", username)
+		}
+		if !auth.CheckAPIKey(apiKey, user.ApiKeyHash.String) {
+			return fmt.Errorf("invalid API key, login failed.")
+		}
+	} else {
+		apiKey = ""
+	}
+
 	// set current user to config
 	if err := s.Config.SetUser(s.Config.GetFilename(), username); err != nil {
 		return err
 	}
+	if err := s.Config.SetAPIKey(s.Config.GetFilename(), apiKey); err != nil {
+		return err
+	}
 
 	// print success message
 	fmt.Printf("user has been logged in: %s.\n", s.Config.CurrentUserName)
+	if !user.ApiKeyHash.Valid {
+		fmt.Println("anyone can log in as this user, run 'gator rotate-key' to protect it with an API key.")
+	}
 
 	return nil
 }
 
 func HandlerRegister(s *State, cmd Command) error {
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("username is required for login.")
-	}
-
 	username := cmd.Args[0]
 
+	// every new user gets an API key, only its hash is stored
+	apiKey, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+
 	// check if user exists in the database before creating a new entry
-	_, err := s.DB.GetUser(context.Background(), username)
+	_, err = s.DB.GetUser(context.Background(), username)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			_, err := s.DB.CreateUser(
 				context.Background(),
 				database.CreateUserParams{
-					ID:        uuid.New(),
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-					Name:      username,
+					ID:         uuid.New(),
+					CreatedAt:  time.Now(),
+					UpdatedAt:  time.Now(),
+					Name:       username,
+					ApiKeyHash: sql.NullString{String: auth.HashAPIKey(apiKey), Valid: true},
 				})
 			if err != nil {
 				return fmt.Errorf("failed to create user: %w", err)
 			}
+		} else {
+			return fmt.Errorf("failed to get user: %w", err)
 		}
 	} else {
 		return fmt.Errorf("user already exist.")
@@ -112,7 +185,11 @@ func HandlerRegister(s *State, cmd Command) error {
 	if err := s.Config.SetUser(s.Config.GetFilename(), username); err != nil {
 		return err
 	}
+	if err := s.Config.SetAPIKey(s.Config.GetFilename(), apiKey); err != nil {
+		return err
+	}
 	fmt.Printf("user has been set: %s.\n", s.Config.CurrentUserName)
+	printAPIKey(apiKey)
 
 	return nil
 }
@@ -142,53 +219,90 @@ func HandlerReset(s *State, cmd Command) error {
 	return nil
 }
 
+// userView is a user as printed by the users command
+type userView struct {
+	Name      string    `json:"name"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 func HandlerUsers(s *State, cmd Command) error {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
 	users, err := s.DB.GetUsers(context.Background())
 	if err != nil {
 		return err
 	}
-	if len(users) == 0 {
+	if len(users) == 0 && format == outputTable {
 		return fmt.Errorf("users database is empty!")
 	}
 
+	views := make([]userView, 0, len(users))
 	for _, v := range users {
-		if v.Name == s.Config.CurrentUserName {
-			fmt.Printf("* %s (current)\n", v.Name)
-		} else {
-			fmt.Printf("* %s\n", v.Name)
-		}
+		views = append(views, userView{
+			Name:      v.Name,
+			Current:   v.Name == s.Config.CurrentUserName,
+			CreatedAt: v.CreatedAt,
+		})
 	}
 
-	return nil
+	return writeList(os.Stdout, format, views)
 }
 
-func HandlerAgg(s *State, cmd Command) error {
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("missing feed URL.")
-	}
-	feedURL := cmd.Args[0]
+// feedView is a feed as printed by the feeds and addfeed commands
+type feedView struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	User          string     `json:"user"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
 
-	feed, err := rss.FetchFeed(context.Background(), feedURL)
+func HandlerAddFeed(s *State, cmd Command, user database.User) error {
+	// the addfeed command accepts two arguments, feed name and feed URL.
+	// with only the feed URL, the name defaults to the feed title.
+	// e.g. addfeed "Hacker News" https://news.ycombinator.com/rss
+	format, err := outputFormat(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed: %w", err)
+		return err
 	}
 
-	fmt.Println(feed)
+	var feedName, rawURL string
+	if len(cmd.Args) == 1 {
+		rawURL = cmd.Args[0]
+	} else {
+		feedName = cmd.Args[0]
+		rawURL = cmd.Args[1]
+	}
 
-	return nil
-}
+	// do a trial fetch so only working feeds are added. the URL may also be
+	// the home page of a blog instead of its feed.
+	feedURL, fetchedFeed, err := resolveFeed(context.Background(), rawURL)
+	if err != nil {
+		return err
+	}
 
-func HandlerAddFeed(s *State, cmd Command, user database.User) error {
-	switch len(cmd.Args) {
-	case 0:
-		return fmt.Errorf("missing feed name and URL.")
-	case 1:
-		return fmt.Errorf("missing feed URL.")
+	if feedName == "" {
+		feedName = strings.TrimSpace(fetchedFeed.Title)
+	}
+	if feedName == "" {
+		return fmt.Errorf("the feed has no title, add it again with a name.")
 	}
 
-	// the addfeed command accepts two arguments, feed name and feed URL
-	feedName := cmd.Args[0]
-	feedURL := cmd.Args[1]
+	// the http and https URLs of a feed share a key, so they are one feed
+	feedKey, err := canonical.Key(feedURL)
+	if err != nil {
+		return err
+	}
+	if existing, err := s.DB.GetFeedByUrlKey(context.Background(), feedKey); err == nil {
+		return fmt.Errorf("feed already exists: %s, follow it instead.", existing.Url)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get feed: %w", err)
+	}
 
 	feed, err := s.DB.CreateFeed(
 		context.Background(),
@@ -198,6 +312,7 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 			UpdatedAt: time.Now().UTC(),
 			Name:      feedName,
 			Url:       feedURL,
+			UrlKey:    feedKey,
 			UserID:    user.ID,
 		},
 	)
@@ -217,46 +332,153 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 		return fmt.Errorf("failed to follow feed: %w", err)
 	}
 
-	fmt.Println("feed has been added.")
-
-	fmt.Printf(`
-	{
-	ID: %s
-	Created At: %s
-	Updated At: %s
-	Name: %s
-	URL: %s
-	UserID: %s	
+	if format == outputTable {
+		fmt.Println("feed has been added.")
 	}
-	`, feed.ID, feed.CreatedAt, feed.UpdatedAt, feed.Name, feed.Url, feed.UserID)
 
-	return nil
+	return writeItem(os.Stdout, format, feedView{
+		ID:            feed.ID.String(),
+		Name:          feed.Name,
+		URL:           feed.Url,
+		User:          user.Name,
+		CreatedAt:     feed.CreatedAt,
+		LastFetchedAt: nullTime(feed.LastFetchedAt),
+	})
 }
 
 func HandlerFeeds(s *State, cmd Command) error {
-	// this function does not accept any arguments
 	// print all feeds in the feeds table
+	// with --errors, print only the feeds that are failing or disabled
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("errors") {
+		return printFeedErrors(s, format)
+	}
 
 	dbFeeds, err := s.DB.GetFeeds(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get feeds: %w", err)
 	}
 
+	views := make([]feedView, 0, len(dbFeeds))
+	for _, record := range dbFeeds {
+		views = append(views, feedView{
+			ID:            record.ID.String(),
+			Name:          record.Name,
+			URL:           record.Url,
+			User:          record.Username,
+			CreatedAt:     record.CreatedAt,
+			LastFetchedAt: nullTime(record.LastFetchedAt),
+		})
+	}
+
+	return writeList(os.Stdout, format, views)
+}
+
+// feedErrorView is a failing feed as printed by feeds --errors
+type feedErrorView struct {
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	Failures      int32      `json:"failures"`
+	Disabled      bool       `json:"disabled"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LastError     string     `json:"last_error"`
+}
+
+func printFeedErrors(s *State, format string) error {
+	dbFeeds, err := s.DB.GetFeedsWithErrors(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	if len(dbFeeds) == 0 && format == outputTable {
+		fmt.Println("no failing feeds.")
+		return nil
+	}
+
+	views := make([]feedErrorView, 0, len(dbFeeds))
 	for _, record := range dbFeeds {
-		fmt.Printf("{Name: %v, URL: %v, User: %v}\n", record.Name, record.Url, record.Username)
+		// disabled feeds are not retried
+		view := feedErrorView{
+			Name:      record.Name,
+			URL:       record.Url,
+			Failures:  record.ConsecutiveFailures,
+			Disabled:  record.DisabledAt.Valid,
+			LastError: record.LastError.String,
+		}
+		if !view.Disabled {
+			view.NextAttemptAt = nullTime(record.NextAttemptAt)
+		}
+		views = append(views, view)
 	}
 
+	return writeList(os.Stdout, format, views)
+}
+
+func HandlerEnableFeed(s *State, cmd Command) error {
+	// the enable-feed command accepts the URL of a feed that agg disabled.
+	// the feed is fetched once more so a feed that is still broken stays
+	// disabled.
+	feedKey, err := canonical.Key(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.DB.GetFeedByUrlKey(context.Background(), feedKey)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("feed not found: %s", cmd.Args[0])
+	} else if err != nil {
+		return fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rss.FetchTimeout)
+	defer cancel()
+	if _, err := rss.FetchFeed(ctx, feed.Url); err != nil {
+		return fmt.Errorf("failed to fetch feed, it stays disabled: %w", err)
+	}
+
+	err = s.DB.EnableFeed(context.Background(), database.EnableFeedParams{
+		ID:        feed.ID,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable feed: %w", err)
+	}
+
+	fmt.Printf("%s has been enabled, agg fetches it on its next tick.\n", feed.Name)
+
 	return nil
 }
 
 func HandlerFollow(s *State, cmd Command, user database.User) error {
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("missing feed URL.")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
 	}
 
 	feedUrl := cmd.Args[0]
 
-	feed, err := s.DB.GetFeedByUrl(context.Background(), feedUrl)
+	feedKey, err := canonical.Key(feedUrl)
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.DB.GetFeedByUrlKey(context.Background(), feedKey)
+	if err == sql.ErrNoRows {
+		// the URL may be the home page of a blog whose feed has been added
+		feedUrl, _, err = resolveFeed(context.Background(), feedUrl)
+		if err != nil {
+			return err
+		}
+		feedKey, err = canonical.Key(feedUrl)
+		if err != nil {
+			return err
+		}
+		feed, err = s.DB.GetFeedByUrlKey(context.Background(), feedKey)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get feed: %w", err)
 	}
@@ -273,44 +495,64 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 		return fmt.Errorf("failed to follow feed: %w", err)
 	}
 
-	fmt.Printf("{Feed name: %v, User: %v}\n", feedFollowRecord.Name_2, feedFollowRecord.Name)
+	if format == outputTable {
+		fmt.Println("feed has been followed.")
+	}
 
-	return nil
+	return writeItem(os.Stdout, format, followView{
+		Feed:       feedFollowRecord.Name_2,
+		URL:        feed.Url,
+		FollowedAt: feedFollowRecord.CreatedAt,
+	})
+}
+
+// followView is a followed feed as printed by the follow and following
+// commands
+type followView struct {
+	Feed       string    `json:"feed"`
+	URL        string    `json:"url"`
+	FollowedAt time.Time `json:"followed_at"`
 }
 
 func HandlerFollowing(s *State, cmd Command, user database.User) error {
 	// this function does not accept any arguments
 	// print all feeds the current user is following
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
 
 	userFeeds, err := s.DB.GetFeedFollowsForUser(context.Background(), user.Name)
 	if err != nil {
 		return fmt.Errorf("failed to get user feeds: %w", err)
 	}
 
-	if len(userFeeds) == 0 {
-		fmt.Println("Feeds: {}")
-	} else {
-		fmt.Printf("User: %s\n", user.Name)
-		fmt.Println("Feeds: {")
-		for _, record := range userFeeds {
-			fmt.Printf("\t%v,\n", record.Name_2)
-		}
-		fmt.Println("}")
+	if len(userFeeds) == 0 && format == outputTable {
+		fmt.Println("you are not following any feeds.")
+		return nil
 	}
 
-	return nil
+	views := make([]followView, 0, len(userFeeds))
+	for _, record := range userFeeds {
+		views = append(views, followView{
+			Feed:       record.Name_2,
+			URL:        record.Url,
+			FollowedAt: record.CreatedAt,
+		})
+	}
+
+	return writeList(os.Stdout, format, views)
 }
 
 func HandlerUnfollow(s *State, cmd Command, user database.User) error {
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("missing feed URL.")
+	feedKey, err := canonical.Key(cmd.Args[0])
+	if err != nil {
+		return err
 	}
 
-	feedUrl := cmd.Args[0]
-
-	err := s.DB.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
+	err = s.DB.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
 		UserID: user.ID,
-		Url:    feedUrl,
+		UrlKey: feedKey,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to unfollow feed: %w", err)
@@ -320,3 +562,135 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 
 	return nil
 }
+
+// postView is a post as printed by the browse command
+type postView struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Feed        string     `json:"feed"`
+	PublishedAt *time.Time `json:"published_at"`
+	URL         string     `json:"url"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+}
+
+func HandlerBrowse(s *State, cmd Command, user database.User) error {
+	// the browse command accepts an optional limit and an --offset flag,
+	// --unread and --starred show only the unread or starred posts
+	// e.g. browse 10 --offset 20 --unread
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	limit := 10
+	if len(cmd.Args) > 0 {
+		var err error
+		limit, err = strconv.Atoi(cmd.Args[0])
+		if err != nil || limit <= 0 {
+			return usageErrorf("invalid limit: %s", cmd.Args[0])
+		}
+	}
+	offset := cmd.Int("offset")
+	if offset < 0 {
+		return usageErrorf("invalid offset: %d", offset)
+	}
+
+	posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+		UserID:      user.ID,
+		UnreadOnly:  cmd.Bool("unread"),
+		StarredOnly: cmd.Bool("starred"),
+		PageLimit:   int32(limit),
+		PageOffset:  int32(offset),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	if len(posts) == 0 && format == outputTable {
+		fmt.Println("no posts found.")
+		return nil
+	}
+
+	views := make([]postView, 0, len(posts))
+	for _, post := range posts {
+		views = append(views, postView{
+			ID:          post.ID.String(),
+			Title:       post.Title,
+			Feed:        post.FeedName,
+			PublishedAt: nullTime(post.PublishedAt),
+			URL:         post.Url,
+			Read:        post.ReadAt.Valid,
+			Starred:     post.StarredAt.Valid,
+		})
+	}
+	if err := writeList(os.Stdout, format, views); err != nil {
+		return err
+	}
+
+	// a full page means there may be more posts to show
+	if len(posts) == limit && format == outputTable {
+		filters := ""
+		if cmd.Bool("unread") {
+			filters += " --unread"
+		}
+		if cmd.Bool("starred") {
+			filters += " --starred"
+		}
+		fmt.Printf("\nnext page: browse %d --offset %d%s\n", limit, offset+limit, filters)
+	}
+
+	return nil
+}
+
+// resolveFeed validates a feed URL given by the user and returns the URL of
+// the feed that was fetched and the fetched feed. when the URL is a web page instead of a feed,
+// the feeds it links to are discovered. a single feed is picked on its own,
+// several are listed for the user to choose from.
+func resolveFeed(ctx context.Context, rawURL string) (string, *rss.Feed, error) {
+	// discovery may fetch several URLs, they all share the time limit
+	ctx, cancel := context.WithTimeout(ctx, rss.FetchTimeout)
+	defer cancel()
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", nil, fmt.Errorf("invalid feed URL, expected an http(s) URL: %s", rawURL)
+	}
+
+	feed, err := rss.FetchFeed(ctx, rawURL)
+	if err == nil {
+		return rawURL, feed, nil
+	}
+
+	contentTypeErr := &rss.ContentTypeError{}
+	if !errors.As(err, &contentTypeErr) {
+		return "", nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+
+	feedURLs, err := rss.Discover(ctx, rawURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to discover feeds: %w", err)
+	}
+
+	switch len(feedURLs) {
+	case 0:
+		return "", nil, fmt.Errorf("%s is not a feed and no feeds were found on the page.", rawURL)
+	case 1:
+		// stdout is kept for the output of the command, e.g. --output json
+		fmt.Fprintf(os.Stderr, "found feed: %s\n", feedURLs[0])
+	default:
+		fmt.Fprintf(os.Stderr, "%s is not a feed, but links to these feeds:\n", rawURL)
+		for _, feedURL := range feedURLs {
+			fmt.Fprintf(os.Stderr, "* %s\n", feedURL)
+		}
+		return "", nil, fmt.Errorf("multiple feeds found, run the command again with one of them.")
+	}
+
+	// a linked feed may still be broken, so it is fetched like the original URL
+	feed, err = rss.FetchFeed(ctx, feedURLs[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+
+	return feedURLs[0], feed, nil
+}
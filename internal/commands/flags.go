@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// UsageError is returned when a command is called with the wrong arguments
+// or flags. main exits with status 2 for it, like the flag package does.
+type UsageError struct {
+	Usage string
+	Err   error
+}
+
+func (e *UsageError) Error() string {
+	if e.Usage == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s\nusage: gator %s", e.Err, e.Usage)
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// usageErrorf is returned by handlers for invalid argument or flag values.
+// the registry adds the usage of the command.
+func usageErrorf(format string, a ...any) error {
+	return &UsageError{Err: fmt.Errorf(format, a...)}
+}
+
+// newFlagSet returns the flag set of a command with its flags defined. the
+// flag set reports errors to the caller instead of printing them.
+func (d Definition) newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(d.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if d.Flags != nil {
+		d.Flags(fs)
+	}
+	return fs
+}
+
+// parse parses the flags of a command and checks the number of positional
+// arguments that are left
+func (d Definition) parse(cmd Command) (Command, error) {
+	fs := d.newFlagSet()
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return Command{}, err
+		}
+		return Command{}, &UsageError{Usage: d.Usage, Err: err}
+	}
+
+	if len(args) < d.MinArgs || (d.MaxArgs >= 0 && len(args) > d.MaxArgs) {
+		return Command{}, &UsageError{Usage: d.Usage, Err: fmt.Errorf("wrong number of arguments.")}
+	}
+
+	return Command{Name: cmd.Name, Args: args, Flags: fs}, nil
+}
+
+// parseFlags parses the flags in args, which may come before, after or in
+// between the positional arguments. the positional arguments are returned in
+// their original order, and every argument after -- is positional, even if
+// it starts with a dash.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		// flag.Parse consumes the -- that ends the flags, so look at the last
+		// argument it consumed
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		// flag.Parse stops at the first non-flag argument, so keep it and
+		// continue parsing the rest
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagValue returns the parsed value of a flag. asking for a flag the
+// command does not define is a programming error.
+func (cmd Command) flagValue(name string) any {
+	if cmd.Flags == nil || cmd.Flags.Lookup(name) == nil {
+		panic(fmt.Sprintf("flag --%s is not defined for command %s", name, cmd.Name))
+	}
+	return cmd.Flags.Lookup(name).Value.(flag.Getter).Get()
+}
+
+func (cmd Command) Bool(name string) bool {
+	return cmd.flagValue(name).(bool)
+}
+
+func (cmd Command) Int(name string) int {
+	return cmd.flagValue(name).(int)
+}
+
+func (cmd Command) String(name string) string {
+	return cmd.flagValue(name).(string)
+}
+
+func (cmd Command) Duration(name string) time.Duration {
+	return cmd.flagValue(name).(time.Duration)
+}
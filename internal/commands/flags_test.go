@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		positional []string
+		feed       string
+		unread     bool
+		err        error
+	}{
+		{
+			name:       "no arguments",
+			args:       []string{},
+			positional: []string{},
+		},
+		{
+			name:       "flags before positional arguments",
+			args:       []string{"--feed", "blog", "--unread", "go", "rust"},
+			positional: []string{"go", "rust"},
+			feed:       "blog",
+			unread:     true,
+		},
+		{
+			name:       "flags mixed with positional arguments",
+			args:       []string{"go", "--feed=blog", "rust", "-unread", "zig"},
+			positional: []string{"go", "rust", "zig"},
+			feed:       "blog",
+			unread:     true,
+		},
+		{
+			name:       "arguments after -- are positional",
+			args:       []string{"--", "tutorial", "-video", "--unread"},
+			positional: []string{"tutorial", "-video", "--unread"},
+		},
+		{
+			name:       "flags before --",
+			args:       []string{"go", "--unread", "--", "-video"},
+			positional: []string{"go", "-video"},
+			unread:     true,
+		},
+		{
+			name:       "-- after a positional argument",
+			args:       []string{"go", "--", "--feed", "blog"},
+			positional: []string{"go", "--feed", "blog"},
+		},
+		{
+			name: "help",
+			args: []string{"go", "-h"},
+			err:  flag.ErrHelp,
+		},
+		{
+			name:       "help after --",
+			args:       []string{"--", "-h"},
+			positional: []string{"-h"},
+		},
+		{
+			name: "undefined flag",
+			args: []string{"tutorial", "-video"},
+			err:  errors.New("flag provided but not defined: -video"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			feed := fs.String("feed", "", "")
+			unread := fs.Bool("unread", false, "")
+
+			positional, err := parseFlags(fs, test.args)
+			if test.err != nil {
+				if err == nil || err.Error() != test.err.Error() {
+					t.Fatalf("parseFlags(%q) error = %v, want %v", test.args, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFlags(%q) error = %v", test.args, err)
+			}
+
+			if !reflect.DeepEqual(positional, test.positional) {
+				t.Errorf("parseFlags(%q) = %q, want %q", test.args, positional, test.positional)
+			}
+			if *feed != test.feed {
+				t.Errorf("parseFlags(%q) --feed = %q, want %q", test.args, *feed, test.feed)
+			}
+			if *unread != test.unread {
+				t.Errorf("parseFlags(%q) --unread = %v, want %v", test.args, *unread, test.unread)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/johndosdos/blog_aggregator/internal/auth"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+)
+
+func HandlerWhoami(s *State, cmd Command, user database.User) error {
+	// the whoami command does not accept any arguments, it prints the user
+	// the commands run as
+	fmt.Printf("logged in as: %s\n", user.Name)
+	fmt.Printf("registered:   %s\n", user.CreatedAt.Format(time.DateTime))
+	if user.ApiKeyHash.Valid {
+		fmt.Println("api key:      set, verified from the config")
+	} else {
+		fmt.Println("api key:      none, run 'gator rotate-key' to create one")
+	}
+
+	return nil
+}
+
+func HandlerRotateKey(s *State, cmd Command, user database.User) error {
+	// the rotate-key command replaces the API key of the current user, the
+	// old key stops working right away
+	apiKey, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.SetUserApiKeyHash(context.Background(), database.SetUserApiKeyHashParams{
+		ID:         user.ID,
+		ApiKeyHash: sql.NullString{String: auth.HashAPIKey(apiKey), Valid: true},
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	if err := s.Config.SetAPIKey(s.Config.GetFilename(), apiKey); err != nil {
+		return err
+	}
+	printAPIKey(apiKey)
+
+	return nil
+}
+
+// printAPIKey shows a new API key. only its hash is stored, so this is the
+// only time the user sees it.
+func printAPIKey(apiKey string) {
+	fmt.Printf("your API key: %s\n", apiKey)
+	fmt.Println("it has been saved to your config. store it somewhere safe, it cannot be shown again.")
+	fmt.Println("use it to log in elsewhere and as the Bearer token of the API.")
+}
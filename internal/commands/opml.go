@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/canonical"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/opml"
+)
+
+// importView is the outcome of the import-opml command
+type importView struct {
+	Created         int `json:"created"`
+	Followed        int `json:"followed"`
+	AlreadyFollowed int `json:"already_followed"`
+	Invalid         int `json:"invalid"`
+}
+
+func HandlerImportOPML(s *State, cmd Command, user database.User) error {
+	// the import-opml command accepts one argument, the OPML file to import
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open OPML file: %w", err)
+	}
+	defer file.Close()
+
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// import everything or nothing, so a failed import can simply be retried
+	tx, err := s.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	created, followed, alreadyFollowed, invalid := 0, 0, 0, 0
+	for _, outline := range doc.Feeds() {
+		// feeds are not fetched here, broken feeds show up in feeds --errors
+		// once the aggregator has tried them
+		feedURL, feedKey, ok := opmlFeedURL(outline.XMLURL)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "skipping invalid feed URL: %s\n", outline.XMLURL)
+			invalid++
+			continue
+		}
+
+		feed, err := qtx.GetFeedByUrlKey(ctx, feedKey)
+		if err == sql.ErrNoRows {
+			feedName := strings.TrimSpace(outline.Name())
+			if feedName == "" {
+				feedName = feedURL
+			}
+
+			feed, err = qtx.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+				UpdatedAt: time.Now().UTC(),
+				Name:      feedName,
+				Url:       feedURL,
+				UrlKey:    feedKey,
+				UserID:    user.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create feed %s: %w", feedURL, err)
+			}
+			created++
+		} else if err != nil {
+			return fmt.Errorf("failed to get feed %s: %w", feedURL, err)
+		}
+
+		_, err = qtx.GetFeedFollow(ctx, database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+		if err == nil {
+			alreadyFollowed++
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get feed follow %s: %w", feedURL, err)
+		}
+
+		_, err = qtx.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to follow feed %s: %w", feedURL, err)
+		}
+		followed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	if format == outputTable {
+		fmt.Println("OPML import success!")
+	}
+
+	return writeItem(os.Stdout, format, importView{
+		Created:         created,
+		Followed:        followed,
+		AlreadyFollowed: alreadyFollowed,
+		Invalid:         invalid,
+	})
+}
+
+func HandlerExportOPML(s *State, cmd Command, user database.User) error {
+	// the export-opml command writes the feeds the user follows to stdout, or
+	// to the file given as the only argument. with --all, every feed is written.
+	// e.g. export-opml --all feeds.opml
+
+	ctx := context.Background()
+	doc := &opml.OPML{
+		Version: "2.0",
+		Head: opml.Head{
+			Title:       fmt.Sprintf("gator feeds of %s", user.Name),
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	// feeds have no folders or tags, so the outlines are flat
+	if cmd.Bool("all") {
+		doc.Head.Title = "gator feeds"
+
+		dbFeeds, err := s.DB.GetFeeds(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get feeds: %w", err)
+		}
+		for _, record := range dbFeeds {
+			doc.Body.Outlines = append(doc.Body.Outlines, feedOutline(record.Name, record.Url))
+		}
+	} else {
+		userFeeds, err := s.DB.GetFeedFollowsForUser(ctx, user.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get user feeds: %w", err)
+		}
+		for _, record := range userFeeds {
+			doc.Body.Outlines = append(doc.Body.Outlines, feedOutline(record.Name_2, record.Url))
+		}
+	}
+
+	if len(cmd.Args) == 0 {
+		return opml.Write(os.Stdout, doc)
+	}
+
+	file, err := os.Create(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create OPML file: %w", err)
+	}
+	defer file.Close()
+
+	if err := opml.Write(file, doc); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close OPML file: %w", err)
+	}
+
+	fmt.Printf("%d feeds exported to %s.\n", len(doc.Body.Outlines), cmd.Args[0])
+
+	return nil
+}
+
+func feedOutline(name, feedURL string) opml.Outline {
+	return opml.Outline{
+		Text:   name,
+		Title:  name,
+		Type:   "rss",
+		XMLURL: feedURL,
+	}
+}
+
+// opmlFeedURL returns the trimmed URL and the key of a feed URL from an OPML
+// file, and false when it is not an http(s) URL
+func opmlFeedURL(rawURL string) (string, string, bool) {
+	feedURL := strings.TrimSpace(rawURL)
+	parsedURL, err := url.Parse(feedURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return "", "", false
+	}
+
+	feedKey, err := canonical.Key(feedURL)
+	if err != nil {
+		return "", "", false
+	}
+
+	return feedURL, feedKey, true
+}
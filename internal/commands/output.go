@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// the formats of the --output flag
+const (
+	outputTable  = "table"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputYAML   = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputNDJSON, outputYAML}
+
+// outputFlag defines the --output flag shared by the commands that print
+// records
+func outputFlag(fs *flag.FlagSet) {
+	fs.String("output", outputTable, "output format: "+strings.Join(outputFormats, ", "))
+}
+
+// outputFormat returns the --output format of a command
+func outputFormat(cmd Command) (string, error) {
+	format := strings.ToLower(cmd.String("output"))
+	if !slices.Contains(outputFormats, format) {
+		return "", usageErrorf("invalid output format: %s. use one of %s.", format, strings.Join(outputFormats, ", "))
+	}
+	return format, nil
+}
+
+// writeList writes records, a slice of structs, in the given format. the
+// json tags of the struct name the fields and the table columns.
+func writeList(w io.Writer, format string, records any) error {
+	list := reflect.ValueOf(records)
+	if list.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot write %T as a list", records)
+	}
+
+	switch format {
+	case outputJSON:
+		// an empty list is [], not null
+		if list.IsNil() {
+			records = []any{}
+		}
+		return writeJSON(w, records, "  ")
+	case outputNDJSON:
+		for i := range list.Len() {
+			if err := writeJSON(w, list.Index(i).Interface(), ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputYAML:
+		if list.Len() == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		for i := range list.Len() {
+			if err := writeYAML(w, list.Index(i), "- "); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return writeTable(w, list)
+	}
+}
+
+// writeItem writes a single record, a struct, in the given format
+func writeItem(w io.Writer, format string, record any) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, record, "  ")
+	case outputNDJSON:
+		return writeJSON(w, record, "")
+	case outputYAML:
+		return writeYAML(w, reflect.ValueOf(record), "")
+	default:
+		list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(record)), 0, 1)
+		return writeTable(w, reflect.Append(list, reflect.ValueOf(record)))
+	}
+}
+
+func writeJSON(w io.Writer, v any, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	return encoder.Encode(v)
+}
+
+// recordField is a field of a record struct with its json name
+type recordField struct {
+	Name  string
+	Value reflect.Value
+}
+
+// recordFields returns the fields of a record struct that have a json name
+func recordFields(record reflect.Value) []recordField {
+	fields := []recordField{}
+	for i := range record.NumField() {
+		name, _, _ := strings.Cut(record.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, recordField{Name: name, Value: record.Field(i)})
+	}
+	return fields
+}
+
+// writeTable writes the records as a table aligned with a tabwriter, with a
+// header row made of the json names of the fields
+func writeTable(w io.Writer, list reflect.Value) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{}
+	for _, field := range recordFields(reflect.New(list.Type().Elem()).Elem()) {
+		header = append(header, strings.ToUpper(strings.ReplaceAll(field.Name, "_", " ")))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for i := range list.Len() {
+		cells := []string{}
+		for _, field := range recordFields(list.Index(i)) {
+			cells = append(cells, tableCell(field.Value))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func tableCell(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "-"
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Local().Format(time.DateTime)
+	case bool:
+		if value {
+			return "yes"
+		}
+		return "no"
+	case string:
+		if value == "" {
+			return "-"
+		}
+		// a tab or a newline would break the alignment
+		return strings.Join(strings.Fields(value), " ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// writeYAML writes a record as a YAML mapping. prefix goes before the first
+// key, "- " makes the mapping an item of a sequence.
+func writeYAML(w io.Writer, record reflect.Value, prefix string) error {
+	indent := strings.Repeat(" ", len(prefix))
+	for i, field := range recordFields(record) {
+		if i > 0 {
+			prefix = indent
+		}
+		if _, err := fmt.Fprintf(w, "%s%s: %s\n", prefix, field.Name, yamlScalar(field.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plainYAMLPattern matches the strings that are safe to write unquoted
+var plainYAMLPattern = regexp.MustCompile(`^[A-Za-z0-9_./(][A-Za-z0-9 _./:@%+,()'&?=#~!-]*$`)
+
+func yamlScalar(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case string:
+		if yamlNeedsQuotes(value) {
+			return strconv.Quote(value)
+		}
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// yamlNeedsQuotes reports whether a string would be read back as something
+// else, e.g. a number, a boolean or a comment, when written unquoted
+func yamlNeedsQuotes(s string) bool {
+	if !plainYAMLPattern.MatchString(s) || strings.HasSuffix(s, " ") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// nullTime returns the time of a nullable column, nil when it is NULL
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
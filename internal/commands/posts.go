@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/canonical"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+)
+
+// dateLayouts are the date formats accepted by the flags that take a date,
+// dates without a zone are in local time
+var dateLayouts = []string{
+	time.RFC3339,
+	time.DateTime,
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+func HandlerRead(s *State, cmd Command, user database.User) error {
+	// the read command accepts one argument, the ID of the post shown by browse
+	post, err := getPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark post as read: %w", err)
+	}
+
+	fmt.Printf("marked as read: %s\n", post.Title)
+
+	return nil
+}
+
+func HandlerUnread(s *State, cmd Command, user database.User) error {
+	post, err := getPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark post as unread: %w", err)
+	}
+
+	fmt.Printf("marked as unread: %s\n", post.Title)
+
+	return nil
+}
+
+func HandlerStar(s *State, cmd Command, user database.User) error {
+	post, err := getPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		StarredAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to star post: %w", err)
+	}
+
+	fmt.Printf("starred: %s\n", post.Title)
+
+	return nil
+}
+
+func HandlerUnstar(s *State, cmd Command, user database.User) error {
+	post, err := getPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unstar post: %w", err)
+	}
+
+	fmt.Printf("unstarred: %s\n", post.Title)
+
+	return nil
+}
+
+func HandlerMarkAllRead(s *State, cmd Command, user database.User) error {
+	// the mark-all-read command marks every post of the followed feeds as read,
+	// --feed limits it to one feed and --before to the older posts
+	// e.g. mark-all-read --feed https://blog.boot.dev/index.xml --before 2024-06-01
+	params := database.MarkAllPostsReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: user.ID,
+	}
+
+	if before := cmd.String("before"); before != "" {
+		t, err := parseDate(before)
+		if err != nil {
+			return err
+		}
+		params.Before = sql.NullTime{Time: t, Valid: true}
+	}
+
+	if feedURL := cmd.String("feed"); feedURL != "" {
+		feed, err := getFollowedFeed(s, user, feedURL)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	count, err := s.DB.MarkAllPostsRead(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("failed to mark posts as read: %w", err)
+	}
+
+	fmt.Printf("%d posts marked as read.\n", count)
+
+	return nil
+}
+
+// getPost returns the post with the ID given by the user, which must be a
+// post of a feed they follow
+func getPost(s *State, user database.User, rawID string) (database.GetPostForUserRow, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.GetPostForUserRow{}, usageErrorf("invalid post ID: %s", rawID)
+	}
+
+	post, err := s.DB.GetPostForUser(context.Background(), database.GetPostForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err == sql.ErrNoRows {
+		return database.GetPostForUserRow{}, fmt.Errorf("post not found: %s", rawID)
+	}
+	if err != nil {
+		return database.GetPostForUserRow{}, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	return post, nil
+}
+
+// getFollowedFeed returns the feed with the given URL, which the user must
+// follow
+func getFollowedFeed(s *State, user database.User, feedURL string) (database.Feed, error) {
+	feedKey, err := canonical.Key(feedURL)
+	if err != nil {
+		return database.Feed{}, usageErrorf("invalid feed URL: %s", feedURL)
+	}
+
+	feed, err := s.DB.GetFeedByUrlKey(context.Background(), feedKey)
+	if err == sql.ErrNoRows {
+		return database.Feed{}, fmt.Errorf("feed not found: %s", feedURL)
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	_, err = s.DB.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err == sql.ErrNoRows {
+		return database.Feed{}, fmt.Errorf("you are not following %s.", feed.Url)
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to get feed follow: %w", err)
+	}
+
+	return feed, nil
+}
+
+// parseDate parses a date given by the user and returns it in UTC, like the
+// timestamps in the database
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, usageErrorf("invalid date: %s, expected e.g. 2024-06-01 or 2024-06-01 15:04", value)
+}
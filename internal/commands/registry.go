@@ -0,0 +1,385 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/rss"
+)
+
+// Definition describes a command for the registry: how it is called, what it
+// does and how it runs. exactly one of Handler and LoggedInHandler is set,
+// LoggedInHandler runs through MiddlewareLoggedIn.
+type Definition struct {
+	Name        string
+	Usage       string
+	Description string
+	// Details is printed by help <command> after the description
+	Details string
+	// MinArgs and MaxArgs bound the number of positional arguments left
+	// after the flags are parsed, MaxArgs is unlimited when it is negative
+	MinArgs int
+	MaxArgs int
+	// Flags defines the flags of the command on its flag set, the handler
+	// reads the parsed values from the Command
+	Flags           func(fs *flag.FlagSet)
+	Handler         func(*State, Command) error
+	LoggedInHandler func(*State, Command, database.User) error
+}
+
+// NeedsLogin reports whether the command runs as the current user
+func (d Definition) NeedsLogin() bool {
+	return d.LoggedInHandler != nil
+}
+
+// NewCommands returns the registry with every command registered
+func NewCommands() *Commands {
+	c := &Commands{
+		Handlers:    make(map[string]func(*State, Command) error),
+		Definitions: make(map[string]Definition),
+	}
+
+	c.Register(Definition{
+		Name:        "help",
+		Usage:       "help [command]",
+		Description: "list the commands, or show how to use one of them",
+		MaxArgs:     1,
+		Handler:     c.handlerHelp,
+	})
+	c.Register(Definition{
+		Name:        "register",
+		Usage:       "register <username>",
+		Description: "create a user with a new API key and log in as them",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Handler:     HandlerRegister,
+	})
+	c.Register(Definition{
+		Name:        "login",
+		Usage:       "login [flags] <username>",
+		Description: "log in as an existing user",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("key", "", "API key of the user, required when the user has one")
+		},
+		Handler: HandlerLogin,
+	})
+	c.Register(Definition{
+		Name:            "whoami",
+		Usage:           "whoami",
+		Description:     "show the user the commands run as",
+		LoggedInHandler: HandlerWhoami,
+	})
+	c.Register(Definition{
+		Name:            "rotate-key",
+		Usage:           "rotate-key",
+		Description:     "replace your API key, the old key stops working",
+		LoggedInHandler: HandlerRotateKey,
+	})
+	c.Register(Definition{
+		Name:        "users",
+		Usage:       "users [flags]",
+		Description: "list every user",
+		Flags:       outputFlag,
+		Handler:     HandlerUsers,
+	})
+	c.Register(Definition{
+		Name:        "reset",
+		Usage:       "reset",
+		Description: "delete every user, feed and follow",
+		Handler:     HandlerReset,
+	})
+	c.Register(Definition{
+		Name:        "agg",
+		Usage:       "agg [flags] <time between requests>",
+		Description: "fetch the feeds continuously and save their posts",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Flags: func(fs *flag.FlagSet) {
+			fs.Int("workers", 4, "number of feeds fetched in parallel")
+			fs.Int("batch", 20, "number of feeds claimed on every tick")
+			fs.Duration("timeout", rss.FetchTimeout, "time limit for fetching a single feed")
+			fs.Int("max-failures", 10, "number of failed fetches in a row that disables a feed")
+		},
+		Handler: HandlerAgg,
+	})
+	c.Register(Definition{
+		Name:        "serve",
+		Usage:       "serve [flags]",
+		Description: "serve the users, feeds, follows and posts as a JSON REST API",
+		Details: `routes: GET/POST /users, GET /users/me, GET/POST /feeds,
+GET/POST/DELETE /follows, GET /posts, GET /posts/{id},
+PUT/DELETE /posts/{id}/read, PUT/DELETE /posts/{id}/star and
+POST /posts/mark-all-read. lists take limit and offset parameters.
+every request but POST /users, which returns the API key of the new
+user, needs an API key: Authorization: Bearer <api key>.`,
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("addr", ":8080", "address to listen on")
+		},
+		Handler: HandlerServe,
+	})
+	c.Register(Definition{
+		Name:        "feeds",
+		Usage:       "feeds [flags]",
+		Description: "list every feed, or only the failing ones",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("errors", false, "show only failing and disabled feeds")
+			outputFlag(fs)
+		},
+		Handler: HandlerFeeds,
+	})
+	c.Register(Definition{
+		Name:        "enable-feed",
+		Usage:       "enable-feed <url>",
+		Description: "fetch a disabled feed again and enable it if it works",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Handler:     HandlerEnableFeed,
+	})
+	c.Register(Definition{
+		Name:            "addfeed",
+		Usage:           "addfeed [flags] [name] <url>",
+		Description:     "add a feed and follow it, the name defaults to the feed title",
+		MinArgs:         1,
+		MaxArgs:         2,
+		Flags:           outputFlag,
+		LoggedInHandler: HandlerAddFeed,
+	})
+	c.Register(Definition{
+		Name:            "follow",
+		Usage:           "follow [flags] <url>",
+		Description:     "follow a feed that has been added",
+		MinArgs:         1,
+		MaxArgs:         1,
+		Flags:           outputFlag,
+		LoggedInHandler: HandlerFollow,
+	})
+	c.Register(Definition{
+		Name:            "following",
+		Usage:           "following [flags]",
+		Description:     "list the feeds you follow",
+		Flags:           outputFlag,
+		LoggedInHandler: HandlerFollowing,
+	})
+	c.Register(Definition{
+		Name:            "unfollow",
+		Usage:           "unfollow <url>",
+		Description:     "stop following a feed",
+		MinArgs:         1,
+		MaxArgs:         1,
+		LoggedInHandler: HandlerUnfollow,
+	})
+	c.Register(Definition{
+		Name:        "browse",
+		Usage:       "browse [flags] [limit]",
+		Description: "show the latest posts from the feeds you follow",
+		MaxArgs:     1,
+		Flags: func(fs *flag.FlagSet) {
+			fs.Int("offset", 0, "number of posts to skip")
+			fs.Bool("unread", false, "show only the posts you have not read")
+			fs.Bool("starred", false, "show only the posts you starred")
+			outputFlag(fs)
+		},
+		LoggedInHandler: HandlerBrowse,
+	})
+	c.Register(Definition{
+		Name:        "search",
+		Usage:       "search [flags] <query>",
+		Description: "search the posts of the feeds you follow",
+		Details: `every word must match, "quoted words" match as a phrase, word* matches
+words starting with word, -word excludes the posts with word and OR
+matches either side. punctuation is ignored, like in the posts, so C++
+searches for c. put -- before a query with -word so it is not read as a
+flag, e.g. gator search -- '"go generics"' tutorial* -video`,
+		MinArgs: 1,
+		MaxArgs: -1,
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("feed", "", "search only the posts of this feed")
+			fs.String("since", "", "search only the posts published since this date, e.g. 2024-06-01")
+			fs.Int("limit", 20, "maximum number of posts to show")
+			outputFlag(fs)
+		},
+		LoggedInHandler: HandlerSearch,
+	})
+	c.Register(Definition{
+		Name:        "tui",
+		Usage:       "tui",
+		Description: "read the posts of the feeds you follow in a full-screen reader",
+		Details: `keys: j/k or the arrows move, h/l or tab switch between the feeds and
+the posts, o or enter opens the post in $BROWSER and marks it as read,
+m marks it as read or unread, s stars it, u shows only the unread posts,
+J/K scroll the preview, r loads the posts saved by agg since and q quits.`,
+		LoggedInHandler: HandlerTUI,
+	})
+	c.Register(Definition{
+		Name:            "read",
+		Usage:           "read <post id>",
+		Description:     "mark a post as read",
+		MinArgs:         1,
+		MaxArgs:         1,
+		LoggedInHandler: HandlerRead,
+	})
+	c.Register(Definition{
+		Name:            "unread",
+		Usage:           "unread <post id>",
+		Description:     "mark a post as unread",
+		MinArgs:         1,
+		MaxArgs:         1,
+		LoggedInHandler: HandlerUnread,
+	})
+	c.Register(Definition{
+		Name:            "star",
+		Usage:           "star <post id>",
+		Description:     "star a post to find it later with browse --starred",
+		MinArgs:         1,
+		MaxArgs:         1,
+		LoggedInHandler: HandlerStar,
+	})
+	c.Register(Definition{
+		Name:            "unstar",
+		Usage:           "unstar <post id>",
+		Description:     "remove the star from a post",
+		MinArgs:         1,
+		MaxArgs:         1,
+		LoggedInHandler: HandlerUnstar,
+	})
+	c.Register(Definition{
+		Name:        "mark-all-read",
+		Usage:       "mark-all-read [flags]",
+		Description: "mark every post of the feeds you follow as read",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("feed", "", "mark only the posts of this feed")
+			fs.String("before", "", "mark only the posts published before this date, e.g. 2024-06-01")
+		},
+		LoggedInHandler: HandlerMarkAllRead,
+	})
+	c.Register(Definition{
+		Name:            "import-opml",
+		Usage:           "import-opml [flags] <file>",
+		Description:     "add and follow the feeds of an OPML file",
+		MinArgs:         1,
+		MaxArgs:         1,
+		Flags:           outputFlag,
+		LoggedInHandler: HandlerImportOPML,
+	})
+	c.Register(Definition{
+		Name:        "export-opml",
+		Usage:       "export-opml [flags] [file]",
+		Description: "write the feeds you follow, or every feed, as OPML",
+		MaxArgs:     1,
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("all", false, "export every feed instead of the feeds you follow")
+		},
+		LoggedInHandler: HandlerExportOPML,
+	})
+
+	return c
+}
+
+func (c *Commands) handlerHelp(s *State, cmd Command) error {
+	if len(cmd.Args) == 1 {
+		def, ok := c.Definitions[cmd.Args[0]]
+		if !ok {
+			return c.unknownCommandError(cmd.Args[0])
+		}
+
+		printCommandHelp(def)
+		return nil
+	}
+
+	names := make([]string, 0, len(c.Definitions))
+	width := 0
+	for name := range c.Definitions {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+
+	fmt.Println("usage: gator <command> [arguments]")
+	fmt.Println()
+	fmt.Println("commands:")
+	for _, name := range names {
+		def := c.Definitions[name]
+		description := def.Description
+		if def.NeedsLogin() {
+			description += " (login required)"
+		}
+		fmt.Printf("  %-*s  %s\n", width, name, description)
+	}
+	fmt.Println()
+	fmt.Println("run 'gator help <command>' for details.")
+
+	return nil
+}
+
+// printCommandHelp prints the usage, description and flags of a command
+func printCommandHelp(def Definition) {
+	fmt.Printf("usage: gator %s\n\n", def.Usage)
+	fmt.Printf("%s.\n", capitalize(def.Description))
+	if def.Details != "" {
+		fmt.Printf("\n%s\n\n", def.Details)
+	}
+	if def.NeedsLogin() {
+		fmt.Println("requires a logged in user.")
+	}
+
+	fs := def.newFlagSet()
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Println("\nflags:")
+		fs.SetOutput(os.Stdout)
+		fs.PrintDefaults()
+	}
+}
+
+// unknownCommandError suggests the registered commands closest to name
+func (c *Commands) unknownCommandError(name string) error {
+	suggestions := []string{}
+	for candidate := range c.Definitions {
+		if strings.HasPrefix(candidate, name) || editDistance(name, candidate) <= 2 {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	sort.Strings(suggestions)
+
+	if len(suggestions) == 0 {
+		return fmt.Errorf("unknown command: %s. run 'gator help' to list the commands.", name)
+	}
+	return fmt.Errorf("unknown command: %s. did you mean %s?", name, strings.Join(suggestions, " or "))
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
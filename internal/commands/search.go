@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+)
+
+// searchResultView is a post as printed by the search command
+type searchResultView struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Feed        string     `json:"feed"`
+	PublishedAt *time.Time `json:"published_at"`
+	URL         string     `json:"url"`
+}
+
+func HandlerSearch(s *State, cmd Command, user database.User) error {
+	// the search command accepts the words to search for, e.g.
+	// search "go generics" tutorial*
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	query, err := buildTSQuery(strings.Join(cmd.Args, " "))
+	if err != nil {
+		return err
+	}
+
+	limit := cmd.Int("limit")
+	if limit <= 0 {
+		return usageErrorf("invalid limit: %d", limit)
+	}
+
+	params := database.SearchPostsForUserParams{
+		Query:     query,
+		UserID:    user.ID,
+		PageLimit: int32(limit),
+	}
+
+	if since := cmd.String("since"); since != "" {
+		t, err := parseDate(since)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: t, Valid: true}
+	}
+
+	if feedURL := cmd.String("feed"); feedURL != "" {
+		feed, err := getFollowedFeed(s, user, feedURL)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	posts, err := s.DB.SearchPostsForUser(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("failed to search posts: %w", err)
+	}
+
+	if len(posts) == 0 && format == outputTable {
+		fmt.Println("no posts found.")
+		return nil
+	}
+
+	views := make([]searchResultView, 0, len(posts))
+	for _, post := range posts {
+		views = append(views, searchResultView{
+			ID:          post.ID.String(),
+			Title:       post.Title,
+			Feed:        post.FeedName,
+			PublishedAt: nullTime(post.PublishedAt),
+			URL:         post.Url,
+		})
+	}
+
+	return writeList(os.Stdout, format, views)
+}
+
+// buildTSQuery turns a search query into a PostgreSQL tsquery. all the words
+// must match, "quoted words" match as a phrase, word* matches as a prefix,
+// -word excludes the posts with the word and OR matches either side.
+// everything but letters and digits is dropped, so the result is always a
+// valid tsquery.
+func buildTSQuery(input string) (string, error) {
+	groups := []string{}
+	terms := []string{}
+	endGroup := func() {
+		if len(terms) > 0 {
+			groups = append(groups, strings.Join(terms, " & "))
+			terms = []string{}
+		}
+	}
+
+	for _, token := range searchTokens(input) {
+		if token == "OR" {
+			endGroup()
+			continue
+		}
+
+		negate := false
+		if rest, found := strings.CutPrefix(token, "-"); found {
+			negate = true
+			token = rest
+		}
+
+		term := tsQueryPhrase(token)
+		if term == "" {
+			continue
+		}
+		if negate {
+			term = "!" + term
+		}
+		terms = append(terms, term)
+	}
+	endGroup()
+
+	if len(groups) == 0 {
+		return "", usageErrorf("the search query has no words to search for.")
+	}
+
+	return strings.Join(groups, " | "), nil
+}
+
+// searchTokens splits a search query on whitespace, keeping quoted phrases
+// together without their quotes
+func searchTokens(input string) []string {
+	tokens := []string{}
+	var token strings.Builder
+	quoted := false
+	for _, r := range input {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens
+}
+
+// tsQueryPhrase returns the words of a token as tsquery lexemes that must
+// follow each other, e.g. (go <-> generic:*)
+func tsQueryPhrase(token string) string {
+	lexemes := []string{}
+	for _, word := range strings.Fields(token) {
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(parts) == 0 {
+			continue
+		}
+		if strings.HasSuffix(word, "*") {
+			parts[len(parts)-1] += ":*"
+		}
+		lexemes = append(lexemes, parts...)
+	}
+
+	switch len(lexemes) {
+	case 0:
+		return ""
+	case 1:
+		return lexemes[0]
+	default:
+		return "(" + strings.Join(lexemes, " <-> ") + ")"
+	}
+}
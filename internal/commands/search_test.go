@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{}},
+		{"   ", []string{}},
+		{"go generics", []string{"go", "generics"}},
+		{"  go \t generics\n", []string{"go", "generics"}},
+		{`"go generics" tutorial*`, []string{"go generics", "tutorial*"}},
+		{`-"go generics" -video`, []string{"-go generics", "-video"}},
+		{`go"lang"`, []string{"golang"}},
+		{`"unclosed phrase`, []string{"unclosed phrase"}},
+		{`""`, []string{}},
+		{"C++ OR rust", []string{"C++", "OR", "rust"}},
+	}
+
+	for _, test := range tests {
+		got := searchTokens(test.input)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("searchTokens(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"go", "go"},
+		{"go generics", "go & generics"},
+		{`"go generics"`, "(go <-> generics)"},
+		{`"go generics" tutorial* -video`, "(go <-> generics) & tutorial:* & !video"},
+		{`"go generic*"`, "(go <-> generic:*)"},
+		{`-"go generics"`, "!(go <-> generics)"},
+		{"go OR rust", "go | rust"},
+		{"go generics OR rust traits", "go & generics | rust & traits"},
+		{"OR go OR OR rust OR", "go | rust"},
+		{"or", "or"},
+		// punctuation is dropped like to_tsvector drops it from the posts
+		{"C++ OR rust", "C | rust"},
+		{"node.js", "(node <-> js)"},
+		{"don't", "(don <-> t)"},
+		{"go & rust | !zig", "go & rust & zig"},
+		{"'; DROP TABLE posts; --", "DROP & TABLE & posts"},
+		{"- go", "go"},
+		{"日本語 über", "日本語 & über"},
+	}
+
+	for _, test := range tests {
+		got, err := buildTSQuery(test.input)
+		if err != nil {
+			t.Errorf("buildTSQuery(%q) error = %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("buildTSQuery(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestBuildTSQueryNoWords(t *testing.T) {
+	for _, input := range []string{"", "   ", "OR", `""`, "- -- *", "+++"} {
+		if got, err := buildTSQuery(input); err == nil {
+			t.Errorf("buildTSQuery(%q) = %q, want an error", input, got)
+		}
+	}
+}
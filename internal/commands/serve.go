@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/johndosdos/blog_aggregator/internal/api"
+)
+
+func HandlerServe(s *State, cmd Command) error {
+	// the serve command serves the REST API until it is stopped
+	// e.g. serve --addr :8080
+	server := &http.Server{
+		Addr:              cmd.String("addr"),
+		Handler:           api.NewServer(s.DB),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// stop the server on ctrl-c or when the process is asked to terminate
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	fmt.Printf("serving the API on %s\n", server.Addr)
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("failed to serve the API: %w", err)
+	case <-ctx.Done():
+	}
+
+	// let the requests in flight finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop the server: %w", err)
+	}
+
+	fmt.Println("server has been stopped.")
+
+	return nil
+}
@@ -0,0 +1,12 @@
+package commands
+
+import (
+	"github.com/johndosdos/blog_aggregator/internal/database"
+	"github.com/johndosdos/blog_aggregator/internal/tui"
+)
+
+func HandlerTUI(s *State, cmd Command, user database.User) error {
+	// the tui command does not accept any arguments, it reads the posts saved
+	// by agg until the user quits
+	return tui.Run(s.DB, user)
+}
@@ -9,7 +9,10 @@ import (
 type Config struct {
 	DBUrl           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
-	filename        string
+	// APIKey proves the current user is who they say they are, it is
+	// required for the users that have a key
+	APIKey   string `json:"api_key,omitempty"`
+	filename string
 }
 
 func (c *Config) GetFilename() string {
@@ -22,12 +25,28 @@ func (c *Config) SetUser(jsonFilenameFull, username string) error {
 	}
 
 	c.CurrentUserName = username
-	file, err := openConfigFile(jsonFilenameFull)
+	return c.write(jsonFilenameFull)
+}
+
+// SetAPIKey saves the API key of the current user, an empty key removes it
+func (c *Config) SetAPIKey(jsonFilenameFull, apiKey string) error {
+	c.APIKey = apiKey
+	return c.write(jsonFilenameFull)
+}
+
+func (c *Config) write(jsonFilenameFull string) error {
+	// truncate the file, a shorter config would leave the end of the old one
+	file, err := openConfigFile(jsonFilenameFull, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
+	// config files created before API keys may be readable by everyone
+	if err := file.Chmod(0600); err != nil {
+		return fmt.Errorf("unable to set config file permissions: %w", err)
+	}
+
 	/*
 		Encode the config struct to the JSON file. Be sure that the file has
 		correct flag and permissions.
@@ -40,7 +59,7 @@ func (c *Config) SetUser(jsonFilenameFull, username string) error {
 	return nil
 }
 
-func openConfigFile(jsonFilenameFull string) (*os.File, error) {
+func openConfigFile(jsonFilenameFull string, flag int) (*os.File, error) {
 	// Get home directory path.
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -50,9 +69,10 @@ func openConfigFile(jsonFilenameFull string) (*os.File, error) {
 	// Find .gatorconfig.json file from the home directory.
 	jsonPath := homeDir + "/" + jsonFilenameFull
 
-	// Open a file for read-write.
+	// Open the file with the given flags.
 	// Close the file after operation (read `Read` function).
-	file, err := os.OpenFile(jsonPath, os.O_RDWR|os.O_CREATE, 0644)
+	// the file holds the API key, so only the owner may read it.
+	file, err := os.OpenFile(jsonPath, flag, 0600)
 	if err != nil {
 		return nil, fmt.Errorf("unable to open file: %w, path: %s", err, jsonPath)
 	}
@@ -61,7 +81,7 @@ func openConfigFile(jsonFilenameFull string) (*os.File, error) {
 }
 
 func Read(jsonFilenameFull string) (Config, error) {
-	file, err := openConfigFile(jsonFilenameFull)
+	file, err := openConfigFile(jsonFilenameFull, os.O_RDONLY)
 	if err != nil {
 		return Config{}, err
 	}
@@ -74,17 +74,17 @@ WHERE
     feed_follows.user_id = $1 AND feed_follows.feed_id = (
         SELECT id 
         FROM feeds
-        WHERE url = $2
+        WHERE url_key = $2
     )
 `
 
 type DeleteFeedFollowParams struct {
 	UserID uuid.UUID
-	Url    string
+	UrlKey string
 }
 
 func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.Url)
+	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.UrlKey)
 	return err
 }
 
@@ -97,11 +97,35 @@ func (q *Queries) DeleteUsersFeedFollows(ctx context.Context) error {
 	return err
 }
 
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+	)
+	return i, err
+}
+
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
     feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
     users.name,
-    feeds.name
+    feeds.name,
+    feeds.url
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
@@ -116,6 +140,7 @@ type GetFeedFollowsForUserRow struct {
 	FeedID    uuid.UUID
 	Name      string
 	Name_2    string
+	Url       string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
@@ -135,6 +160,61 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 			&i.FeedID,
 			&i.Name,
 			&i.Name_2,
+			&i.Url,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsPageForUser = `-- name: GetFeedFollowsPageForUser :many
+SELECT
+    feed_follows.feed_id,
+    feed_follows.created_at,
+    feeds.name AS feed_name,
+    feeds.url
+FROM feed_follows
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at, feed_follows.id
+LIMIT $2 OFFSET $3
+`
+
+type GetFeedFollowsPageForUserParams struct {
+	UserID     uuid.UUID
+	PageLimit  int32
+	PageOffset int32
+}
+
+type GetFeedFollowsPageForUserRow struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	FeedName  string
+	Url       string
+}
+
+func (q *Queries) GetFeedFollowsPageForUser(ctx context.Context, arg GetFeedFollowsPageForUserParams) ([]GetFeedFollowsPageForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsPageForUser, arg.UserID, arg.PageLimit, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsPageForUserRow
+	for rows.Next() {
+		var i GetFeedFollowsPageForUserRow
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.CreatedAt,
+			&i.FeedName,
+			&i.Url,
 		); err != nil {
 			return nil, err
 		}
@@ -7,15 +7,78 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET updated_at = $1, next_attempt_at = $3
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE
+        disabled_at IS NULL
+        AND (next_attempt_at IS NULL OR next_attempt_at <= $1)
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_attempt_at, disabled_at, min_refresh_interval, skip_hours, skip_days, url_key
+`
+
+type ClaimFeedsToFetchParams struct {
+	UpdatedAt     time.Time
+	Limit         int32
+	NextAttemptAt sql.NullTime
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.UpdatedAt, arg.Limit, arg.NextAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.NextAttemptAt,
+			&i.DisabledAt,
+			&i.MinRefreshInterval,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id
+INSERT INTO feeds (id, created_at, updated_at, name, url, url_key, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_attempt_at, disabled_at, min_refresh_interval, skip_hours, skip_days, url_key
 `
 
 type CreateFeedParams struct {
@@ -24,6 +87,7 @@ type CreateFeedParams struct {
 	UpdatedAt time.Time
 	Name      string
 	Url       string
+	UrlKey    string
 	UserID    uuid.UUID
 }
 
@@ -34,6 +98,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.UpdatedAt,
 		arg.Name,
 		arg.Url,
+		arg.UrlKey,
 		arg.UserID,
 	)
 	var i Feed
@@ -44,6 +109,17 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.NextAttemptAt,
+		&i.DisabledAt,
+		&i.MinRefreshInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.UrlKey,
 	)
 	return i, err
 }
@@ -57,12 +133,33 @@ func (q *Queries) DeleteFeeds(ctx context.Context) error {
 	return err
 }
 
-const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id FROM feeds WHERE url = $1
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET
+    updated_at = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    next_attempt_at = NULL,
+    disabled_at = NULL
+WHERE id = $1
+`
+
+type EnableFeedParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) EnableFeed(ctx context.Context, arg EnableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const getFeedByUrlKey = `-- name: GetFeedByUrlKey :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_attempt_at, disabled_at, min_refresh_interval, skip_hours, skip_days, url_key FROM feeds WHERE url_key = $1
 `
 
-func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
+func (q *Queries) GetFeedByUrlKey(ctx context.Context, urlKey string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByUrlKey, urlKey)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -71,26 +168,48 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.NextAttemptAt,
+		&i.DisabledAt,
+		&i.MinRefreshInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.UrlKey,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
 SELECT
-    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id,
+    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.last_error, feeds.consecutive_failures, feeds.next_attempt_at, feeds.disabled_at, feeds.min_refresh_interval, feeds.skip_hours, feeds.skip_days, feeds.url_key,
     users.name AS username
 FROM feeds
 JOIN users ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
-	Username  string
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	NextAttemptAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	MinRefreshInterval  int32
+	SkipHours           int32
+	SkipDays            int32
+	UrlKey              string
+	Username            string
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -109,6 +228,148 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 			&i.Name,
 			&i.Url,
 			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.NextAttemptAt,
+			&i.DisabledAt,
+			&i.MinRefreshInterval,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.UrlKey,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsPage = `-- name: GetFeedsPage :many
+SELECT
+    feeds.id,
+    feeds.created_at,
+    feeds.name,
+    feeds.url,
+    feeds.last_fetched_at,
+    users.name AS username
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+ORDER BY feeds.created_at, feeds.id
+LIMIT $1 OFFSET $2
+`
+
+type GetFeedsPageParams struct {
+	PageLimit  int32
+	PageOffset int32
+}
+
+type GetFeedsPageRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	Name          string
+	Url           string
+	LastFetchedAt sql.NullTime
+	Username      string
+}
+
+func (q *Queries) GetFeedsPage(ctx context.Context, arg GetFeedsPageParams) ([]GetFeedsPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsPage, arg.PageLimit, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedsPageRow
+	for rows.Next() {
+		var i GetFeedsPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsWithErrors = `-- name: GetFeedsWithErrors :many
+SELECT
+    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.last_error, feeds.consecutive_failures, feeds.next_attempt_at, feeds.disabled_at, feeds.min_refresh_interval, feeds.skip_hours, feeds.skip_days, feeds.url_key,
+    users.name AS username
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.consecutive_failures > 0 OR feeds.disabled_at IS NOT NULL
+ORDER BY feeds.disabled_at DESC NULLS LAST, feeds.consecutive_failures DESC
+`
+
+type GetFeedsWithErrorsRow struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	NextAttemptAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	MinRefreshInterval  int32
+	SkipHours           int32
+	SkipDays            int32
+	UrlKey              string
+	Username            string
+}
+
+func (q *Queries) GetFeedsWithErrors(ctx context.Context) ([]GetFeedsWithErrorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsWithErrors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedsWithErrorsRow
+	for rows.Next() {
+		var i GetFeedsWithErrorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.NextAttemptAt,
+			&i.DisabledAt,
+			&i.MinRefreshInterval,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.UrlKey,
 			&i.Username,
 		); err != nil {
 			return nil, err
@@ -123,3 +384,142 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	}
 	return items, nil
 }
+
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_attempt_at, disabled_at, min_refresh_interval, skip_hours, skip_days, url_key FROM feeds
+WHERE disabled_at IS NULL
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT $1
+`
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.NextAttemptAt,
+			&i.DisabledAt,
+			&i.MinRefreshInterval,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFailed = `-- name: MarkFeedFailed :exec
+UPDATE feeds
+SET
+    last_error = $2,
+    consecutive_failures = $3,
+    next_attempt_at = $4,
+    disabled_at = $5
+WHERE id = $1
+`
+
+type MarkFeedFailedParams struct {
+	ID                  uuid.UUID
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	NextAttemptAt       sql.NullTime
+	DisabledAt          sql.NullTime
+}
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg MarkFeedFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFailed,
+		arg.ID,
+		arg.LastError,
+		arg.ConsecutiveFailures,
+		arg.NextAttemptAt,
+		arg.DisabledAt,
+	)
+	return err
+}
+
+const markFeedFetched = `-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET
+    last_fetched_at = $2,
+    updated_at = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    next_attempt_at = $3
+WHERE id = $1
+`
+
+type MarkFeedFetchedParams struct {
+	ID            uuid.UUID
+	LastFetchedAt sql.NullTime
+	NextAttemptAt sql.NullTime
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt, arg.NextAttemptAt)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
+WHERE id = $1
+`
+
+type SetFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedRefreshSchedule = `-- name: SetFeedRefreshSchedule :exec
+UPDATE feeds
+SET min_refresh_interval = $2, skip_hours = $3, skip_days = $4
+WHERE id = $1
+`
+
+type SetFeedRefreshScheduleParams struct {
+	ID                 uuid.UUID
+	MinRefreshInterval int32
+	SkipHours          int32
+	SkipDays           int32
+}
+
+func (q *Queries) SetFeedRefreshSchedule(ctx context.Context, arg SetFeedRefreshScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRefreshSchedule,
+		arg.ID,
+		arg.MinRefreshInterval,
+		arg.SkipHours,
+		arg.SkipDays,
+	)
+	return err
+}
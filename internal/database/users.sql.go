@@ -7,22 +7,24 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
-VALUES ($1, $2, $3, $4)
-RETURNING id, created_at, updated_at, name
+INSERT INTO users (id, created_at, updated_at, name, api_key_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, updated_at, name, api_key_hash
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Name       string
+	ApiKeyHash sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -31,6 +33,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.ApiKeyHash,
 	)
 	var i User
 	err := row.Scan(
@@ -38,6 +41,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.ApiKeyHash,
 	)
 	return i, err
 }
@@ -52,7 +56,7 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, api_key_hash FROM users
 WHERE name = $1
 `
 
@@ -64,12 +68,31 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.ApiKeyHash,
+	)
+	return i, err
+}
+
+const getUserByApiKeyHash = `-- name: GetUserByApiKeyHash :one
+SELECT id, created_at, updated_at, name, api_key_hash FROM users
+WHERE api_key_hash = $1
+`
+
+func (q *Queries) GetUserByApiKeyHash(ctx context.Context, apiKeyHash sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByApiKeyHash, apiKeyHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiKeyHash,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, api_key_hash FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -86,6 +109,51 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.ApiKeyHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersPage = `-- name: GetUsersPage :many
+SELECT id, created_at, name FROM users
+ORDER BY created_at, id
+LIMIT $1 OFFSET $2
+`
+
+type GetUsersPageParams struct {
+	PageLimit  int32
+	PageOffset int32
+}
+
+type GetUsersPageRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+}
+
+func (q *Queries) GetUsersPage(ctx context.Context, arg GetUsersPageParams) ([]GetUsersPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersPage, arg.PageLimit, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersPageRow
+	for rows.Next() {
+		var i GetUsersPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Name,
 		); err != nil {
 			return nil, err
 		}
@@ -99,3 +167,20 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const setUserApiKeyHash = `-- name: SetUserApiKeyHash :exec
+UPDATE users
+SET api_key_hash = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetUserApiKeyHashParams struct {
+	ID         uuid.UUID
+	ApiKeyHash sql.NullString
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) SetUserApiKeyHash(ctx context.Context, arg SetUserApiKeyHashParams) error {
+	_, err := q.db.ExecContext(ctx, setUserApiKeyHash, arg.ID, arg.ApiKeyHash, arg.UpdatedAt)
+	return err
+}
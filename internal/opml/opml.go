@@ -0,0 +1,90 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// OPML is an OPML 2.0 document, the format feed readers use to import and
+// export subscription lists
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed subscription, when XMLURL is set, or a folder
+// holding more outlines
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Name returns the title of an outline, falling back to its text
+func (o Outline) Name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+func Parse(r io.Reader) (*OPML, error) {
+	doc := &OPML{}
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return &OPML{}, fmt.Errorf("failed to decode OPML: %w", err)
+	}
+
+	return doc, nil
+}
+
+// Feeds returns every feed outline in the document, walking nested folders
+// depth first
+func (o *OPML) Feeds() []Outline {
+	feeds := []Outline{}
+
+	var walk func(outlines []Outline)
+	walk = func(outlines []Outline) {
+		for _, outline := range outlines {
+			if outline.XMLURL != "" {
+				feeds = append(feeds, outline)
+			}
+			walk(outline.Outlines)
+		}
+	}
+	walk(o.Body.Outlines)
+
+	return feeds
+}
+
+// Write encodes an OPML document, with the XML declaration and indentation
+func Write(w io.Writer, doc *OPML) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode OPML: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package rss
+
+import (
+	"html"
+	"strings"
+)
+
+type AtomFeed struct {
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Link     []AtomLink   `xml:"link"`
+	Author   []AtomAuthor `xml:"author"`
+	Entry    []AtomEntry  `xml:"entry"`
+
+	// Atom has no ttl, but the syndication module is used in Atom feeds too
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+type AtomEntry struct {
+	Title     AtomText     `xml:"title"`
+	Link      []AtomLink   `xml:"link"`
+	Author    []AtomAuthor `xml:"author"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Summary   AtomText     `xml:"summary"`
+	Content   AtomText     `xml:"content"`
+}
+
+type AtomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// AtomText is an Atom text construct. text and html content is plain
+// character data, xhtml content is markup nested inside the element. html
+// content is unescaped like the fields of an RSS feed.
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	switch t.Type {
+	case "xhtml":
+		return strings.TrimSpace(t.InnerXML)
+	case "html":
+		return strings.TrimSpace(html.UnescapeString(t.Text))
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink returns the link to the web page of a feed or entry. a link
+// without a rel attribute is an alternate link, and html pages are preferred.
+func alternateLink(links []AtomLink) string {
+	href := ""
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if href == "" {
+			href = link.Href
+		}
+	}
+
+	return href
+}
+
+func (f *AtomFeed) normalize() *Feed {
+	feed := &Feed{
+		Title:       f.Title.String(),
+		Link:        alternateLink(f.Link),
+		Description: f.Subtitle.String(),
+		Items:       make([]Item, 0, len(f.Entry)),
+		Schedule:    newSchedule("", f.UpdatePeriod, f.UpdateFrequency, RSSSkipHours{}, RSSSkipDays{}),
+	}
+
+	for _, entry := range f.Entry {
+		// prefer the original publication date, updated is required by the
+		// spec so it is the fallback
+		published, ok := ParseDate(entry.Published)
+		if !ok {
+			published, _ = ParseDate(entry.Updated)
+		}
+
+		description := entry.Summary.String()
+		if description == "" {
+			description = entry.Content.String()
+		}
+
+		// entries without their own authors are written by the feed authors
+		authors := entry.Author
+		if len(authors) == 0 {
+			authors = f.Author
+		}
+		names := []string{}
+		for _, author := range authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		feed.Items = append(feed.Items, Item{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Link),
+			Description: description,
+			Author:      strings.Join(names, ", "),
+			Published:   published,
+		})
+	}
+
+	return feed
+}
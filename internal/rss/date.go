@@ -0,0 +1,163 @@
+package rss
+
+import (
+	"strings"
+	"time"
+)
+
+// dateLayouts are the publication date formats seen in real feeds, tried in
+// order. the RSS spec asks for RFC822 but most feeds use RFC1123, and Atom
+// and JSON feeds use RFC3339.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC850,
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+
+	// RFC1123 with single digit days, missing seconds or a missing weekday
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006",
+
+	// RFC1123 with the full weekday or month name
+	"Monday, 2 Jan 2006 15:04:05 -0700",
+	"Monday, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+
+	// RFC1123 with a colon in the offset
+	"Mon, 2 Jan 2006 15:04:05 -07:00",
+
+	// ISO 8601 variants, with and without a zone
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// zoneOffsets maps the zone abbreviations found in feeds to their offsets.
+// time.Parse only knows the abbreviations of the local zone and treats the
+// rest as UTC, which would shift US dates by several hours. abbreviations
+// used by several zones, like IST and BST, are left out on purpose.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"JST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+}
+
+// dateNames are the month and weekday names, which look like zone
+// abbreviations to normalizeDate
+var dateNames = map[string]bool{}
+
+func init() {
+	for month := time.January; month <= time.December; month++ {
+		dateNames[strings.ToLower(month.String())] = true
+		dateNames[strings.ToLower(month.String()[:3])] = true
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		dateNames[strings.ToLower(day.String())] = true
+		dateNames[strings.ToLower(day.String()[:3])] = true
+	}
+}
+
+// ParseDate parses a feed publication date in any of the known layouts and
+// returns it in UTC. ok is false when the date is empty or in an unknown
+// format, so callers can store the item without a date instead of failing.
+// dates without a zone are assumed to be UTC, dates with an unknown zone
+// abbreviation are in an unknown format.
+func ParseDate(value string) (t time.Time, ok bool) {
+	value, ok = normalizeDate(value)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// normalizeDate cleans up the whitespace and replaces the zone abbreviation
+// with its numeric offset. ok is false when the date is empty or has a zone
+// abbreviation that is not in zoneOffsets, which time.Parse would read as
+// UTC.
+func normalizeDate(value string) (string, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	// some feeds write GMT+0100 or (UTC) instead of a plain offset
+	last := strings.Trim(fields[len(fields)-1], "()")
+	if offset, found := strings.CutPrefix(last, "GMT"); found && offset != "" {
+		last = offset
+	}
+	fields[len(fields)-1] = last
+
+	// the zone is not always last, e.g. in Mon Jan 2 15:04:05 MST 2006
+	for i, field := range fields {
+		if !isZoneAbbreviation(field) {
+			continue
+		}
+		offset, found := zoneOffsets[strings.ToUpper(field)]
+		if !found {
+			return "", false
+		}
+		fields[i] = offset
+	}
+
+	return strings.Join(fields, " "), true
+}
+
+// isZoneAbbreviation reports whether a field of a date is a word of up to
+// five letters that is not a month or weekday name
+func isZoneAbbreviation(field string) bool {
+	if len(field) > 5 || dateNames[strings.ToLower(field)] {
+		return false
+	}
+	for _, r := range field {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// RFC1123 and RFC822, with offsets and known abbreviations
+		{"Tue, 10 Jun 2003 04:00:00 GMT", "2003-06-10T04:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 +0200", "2003-06-10T02:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 -07:00", "2003-06-10T11:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 EST", "2003-06-10T09:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 PDT", "2003-06-10T11:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 CET", "2003-06-10T03:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 CEST", "2003-06-10T02:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 JST", "2003-06-09T19:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 utc", "2003-06-10T04:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 Z", "2003-06-10T04:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 GMT+0100", "2003-06-10T03:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00:00 (UTC)", "2003-06-10T04:00:00Z"},
+		{"10 Jun 03 04:00 EDT", "2003-06-10T08:00:00Z"},
+
+		// RFC1123 variants seen in real feeds
+		{"Tue, 3 Jun 2003 04:00:00 +0000", "2003-06-03T04:00:00Z"},
+		{"Tue, 10 Jun 2003 04:00 +0000", "2003-06-10T04:00:00Z"},
+		{"10 Jun 2003 04:00:00 +0000", "2003-06-10T04:00:00Z"},
+		{"10 Jun 2003", "2003-06-10T00:00:00Z"},
+		{"Tuesday, 10 Jun 2003 04:00:00 +0000", "2003-06-10T04:00:00Z"},
+		{"Tue, 10 June 2003 04:00:00 +0000", "2003-06-10T04:00:00Z"},
+		{"  Tue,  10 Jun 2003\n 04:00:00 GMT ", "2003-06-10T04:00:00Z"},
+		{"TUE, 10 JUN 2003 04:00:00 GMT", "2003-06-10T04:00:00Z"},
+
+		// the zone in the middle of the date
+		{"Tue Jun 10 04:00:00 CST 2003", "2003-06-10T10:00:00Z"},
+		{"Tue Jun 10 04:00:00 -0700 2003", "2003-06-10T11:00:00Z"},
+
+		// RFC3339 and ISO 8601
+		{"2003-06-10T04:00:00Z", "2003-06-10T04:00:00Z"},
+		{"2003-06-10T04:00:00.123Z", "2003-06-10T04:00:00.123Z"},
+		{"2003-06-10T04:00:00+02:00", "2003-06-10T02:00:00Z"},
+		{"2003-06-10T04:00:00+0200", "2003-06-10T02:00:00Z"},
+		{"2003-06-10T04:00+02:00", "2003-06-10T02:00:00Z"},
+		{"2003-06-10T04:00:00", "2003-06-10T04:00:00Z"},
+		{"2003-06-10 04:00:00 -0500", "2003-06-10T09:00:00Z"},
+		{"2003-06-10 04:00:00 EDT", "2003-06-10T08:00:00Z"},
+		{"2003-06-10 04:00:00", "2003-06-10T04:00:00Z"},
+		{"2003-06-10 04:00", "2003-06-10T04:00:00Z"},
+		{"2003-06-10", "2003-06-10T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		want, err := time.Parse(time.RFC3339Nano, test.want)
+		if err != nil {
+			t.Fatalf("invalid test date %q: %v", test.want, err)
+		}
+
+		got, ok := ParseDate(test.input)
+		if !ok {
+			t.Errorf("ParseDate(%q) failed, want %s", test.input, test.want)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("ParseDate(%q) = %s, want %s", test.input, got.Format(time.RFC3339Nano), test.want)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"   ",
+		"yesterday",
+		"10/06/2003",
+		"Tue, 10 Jun 2003 25:00:00 GMT",
+		"Tue, 10 Jun 2003 04:00:00 XYZ",
+		// abbreviations of several zones or of none are not read as UTC
+		"Tue, 10 Jun 2003 04:00:00 BST",
+		"Tue, 10 Jun 2003 04:00:00 IST",
+		"Tue Jun 10 04:00:00 IST 2003",
+		"2003-06-10 04:00:00 BST",
+	} {
+		if got, ok := ParseDate(input); ok {
+			t.Errorf("ParseDate(%q) = %s, want it to fail", input, got.Format(time.RFC3339))
+		}
+	}
+}
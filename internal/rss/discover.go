@@ -0,0 +1,135 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// feedLinkTypes are the <link type> values that point to a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+// commonFeedPaths are tried on the host of the page when a page does not
+// link to its feeds
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/feed.xml",
+	"/rss.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+var (
+	linkTagPattern   = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attributePattern = regexp.MustCompile(`(?s)([a-zA-Z][a-zA-Z0-9_:-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// Discover returns the feeds of the web page at pageURL. it looks for
+// <link rel="alternate"> tags with a feed type first, and falls back to
+// trying the paths most blogs serve their feed at.
+func Discover(ctx context.Context, pageURL string) ([]string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	page, err := fetchPage(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	feeds := []string{}
+	seen := map[string]bool{}
+	for _, href := range feedLinks(page) {
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		feedURL := base.ResolveReference(ref).String()
+		if !seen[feedURL] {
+			seen[feedURL] = true
+			feeds = append(feeds, feedURL)
+		}
+	}
+	if len(feeds) > 0 {
+		return feeds, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		feedURL := base.ResolveReference(&url.URL{Path: path}).String()
+		if seen[feedURL] {
+			continue
+		}
+		seen[feedURL] = true
+
+		if _, err := FetchFeed(ctx, feedURL); err == nil {
+			feeds = append(feeds, feedURL)
+		}
+	}
+
+	return feeds, nil
+}
+
+// fetchPage downloads a web page, up to MaxFeedSize bytes of it
+func fetchPage(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", &HTTPError{URL: pageURL, StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxFeedSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read page: %w", err)
+	}
+
+	return string(body), nil
+}
+
+// feedLinks returns the href of every <link rel="alternate"> tag in an HTML
+// page whose type is a feed type
+func feedLinks(page string) []string {
+	hrefs := []string{}
+	for _, tag := range linkTagPattern.FindAllString(page, -1) {
+		attributes := map[string]string{}
+		for _, match := range attributePattern.FindAllStringSubmatch(tag, -1) {
+			value := strings.Trim(match[2], `"'`)
+			attributes[strings.ToLower(match[1])] = html.UnescapeString(strings.TrimSpace(value))
+		}
+
+		// rel is a space separated list, e.g. "alternate home"
+		isAlternate := false
+		for _, rel := range strings.Fields(strings.ToLower(attributes["rel"])) {
+			isAlternate = isAlternate || rel == "alternate"
+		}
+
+		linkType := strings.ToLower(attributes["type"])
+		if isAlternate && feedLinkTypes[linkType] && attributes["href"] != "" {
+			hrefs = append(hrefs, attributes["href"])
+		}
+	}
+
+	return hrefs
+}
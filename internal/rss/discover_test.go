@@ -0,0 +1,65 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title></channel></rss>`
+
+// an HTML page that is not valid XML, like most pages
+const testPage = `<!DOCTYPE html>
+<html lang=en>
+<head><title>Example</title><meta charset=utf-8></head>
+<body><p>no feed links here<br></body>
+</html>`
+
+func TestFetchFeedHTMLPage(t *testing.T) {
+	for _, contentType := range []string{"", "text/plain; charset=utf-8", "text/html", "application/xml"} {
+		t.Run(contentType, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// an empty Content-Type header keeps the server from sniffing one
+				w.Header()["Content-Type"] = []string{contentType}
+				w.Write([]byte(testPage))
+			}))
+			defer server.Close()
+
+			_, err := FetchFeed(context.Background(), server.URL)
+			contentTypeErr := &ContentTypeError{}
+			if !errors.As(err, &contentTypeErr) {
+				t.Fatalf("FetchFeed error = %v, want a ContentTypeError", err)
+			}
+		})
+	}
+}
+
+func TestDiscoverCommonPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/2024/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testRSS))
+	})
+	// a feed next to the page is not a feed of the site
+	mux.HandleFunc("/blog/2024/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testRSS))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	feeds, err := Discover(context.Background(), server.URL+"/blog/2024/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{server.URL + "/feed.xml"}; !reflect.DeepEqual(feeds, want) {
+		t.Errorf("Discover = %q, want %q", feeds, want)
+	}
+}
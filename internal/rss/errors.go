@@ -0,0 +1,99 @@
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxFeedSize is the largest feed body FetchFeed reads, in bytes
+const MaxFeedSize = 10 << 20
+
+// FetchTimeout is the time limit for fetching a feed when the user has not
+// set one. the fetches have no time limit of their own, callers set it with
+// the deadline of the context.
+const FetchTimeout = 30 * time.Second
+
+// HTTPError is returned when the server answers with a status other than
+// 200 OK or 304 Not Modified
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the server asked us to wait before the next
+	// request, from the Retry-After header of a 429 or 503 response
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status fetching %s: %s", e.URL, e.Status)
+}
+
+// Temporary reports whether the request may succeed when retried later.
+// timeouts, rate limits and server errors are temporary, a missing feed or a
+// refused request is not.
+func (e *HTTPError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+// parseRetryAfter reads the Retry-After header of a 429 Too Many Requests or
+// 503 Service Unavailable response. the header is either a number of seconds
+// or an HTTP date.
+func parseRetryAfter(res *http.Response, now time.Time) time.Duration {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	value := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// ContentTypeError is returned when the response is not a feed, e.g. an HTML
+// page served in place of the feed
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	if e.ContentType == "" {
+		return fmt.Sprintf("%s is not a feed, it has no content type", e.URL)
+	}
+	return fmt.Sprintf("%s is not a feed, content type: %s", e.URL, e.ContentType)
+}
+
+// TooLargeError is returned when the response body is bigger than Limit bytes
+type TooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("feed %s is larger than %d bytes", e.URL, e.Limit)
+}
+
+// AddressError is returned by FetchPublicFeed when a connection would go to
+// a loopback, private or link-local address
+type AddressError struct {
+	Address string
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("refused to connect to %s, it is not a public address", e.Address)
+}
@@ -0,0 +1,110 @@
+package rss
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+)
+
+// JSONFeed is a JSON Feed 1.1 document. the single author fields are from
+// version 1.0 and are still common.
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	Description string           `json:"description"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Author      *JSONFeedAuthor  `json:"author"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+type JSONFeedItem struct {
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	Summary       string           `json:"summary"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []JSONFeedAuthor `json:"authors"`
+	Author        *JSONFeedAuthor  `json:"author"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// isJSONFeed reports whether a response is a JSON Feed, either by its
+// content type or, since many servers send text/plain, by its first byte
+func isJSONFeed(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/feed+json" || mediaType == "application/json" {
+		return true
+	}
+
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+// authorNames joins the author names, falling back to the 1.0 author field
+func authorNames(authors []JSONFeedAuthor, author *JSONFeedAuthor) string {
+	if len(authors) == 0 && author != nil {
+		authors = []JSONFeedAuthor{*author}
+	}
+
+	names := []string{}
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (f *JSONFeed) normalize() *Feed {
+	feed := &Feed{
+		Title:       f.Title,
+		Link:        f.HomePageURL,
+		Description: f.Description,
+		Items:       make([]Item, 0, len(f.Items)),
+	}
+	feedAuthor := authorNames(f.Authors, f.Author)
+
+	for _, item := range f.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+
+		published, ok := ParseDate(item.DatePublished)
+		if !ok {
+			published, _ = ParseDate(item.DateModified)
+		}
+
+		// items without their own authors are written by the feed authors
+		author := authorNames(item.Authors, item.Author)
+		if author == "" {
+			author = feedAuthor
+		}
+
+		feed.Items = append(feed.Items, Item{
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			Author:      author,
+			Published:   published,
+		})
+	}
+
+	return feed
+}
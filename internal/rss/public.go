@@ -0,0 +1,66 @@
+package rss
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+)
+
+// publicClient is the client of FetchPublicFeed. every connection, including
+// the ones of redirects, is checked after the host name is resolved, so a
+// name that resolves to an internal address is refused too.
+var publicClient = newPublicClient()
+
+func newPublicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: FetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addr) {
+				return &AddressError{Address: host}
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would connect to the feed for us, past the check
+	transport.Proxy = nil
+
+	return &http.Client{Transport: transport}
+}
+
+// isPublicAddr reports whether addr is reachable from the internet, as
+// opposed to the machine itself or its local networks
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is
+// as internal as the private ranges
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// FetchPublicFeed is FetchFeed for URLs from untrusted users, e.g. the
+// clients of the API. it refuses to connect to loopback, private and
+// link-local addresses, so the URL cannot reach the server's own network.
+func FetchPublicFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	result, err := fetchFeed(ctx, publicClient, feedURL, CacheValidators{})
+	if err != nil {
+		return &Feed{}, err
+	}
+
+	return result.Feed, nil
+}
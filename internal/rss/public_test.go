@@ -0,0 +1,60 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+
+	for _, test := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(test.addr)); got != test.want {
+			t.Errorf("isPublicAddr(%s) = %t, want %t", test.addr, got, test.want)
+		}
+	}
+}
+
+func TestFetchPublicFeedLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	// the same server is fine for FetchFeed
+	if _, err := FetchFeed(context.Background(), server.URL); err != nil {
+		t.Fatalf("FetchFeed error = %v", err)
+	}
+
+	_, err := FetchPublicFeed(context.Background(), server.URL)
+	addressErr := &AddressError{}
+	if !errors.As(err, &addressErr) {
+		t.Fatalf("FetchPublicFeed error = %v, want an AddressError", err)
+	}
+}
@@ -1,20 +1,50 @@
 package rss
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"html"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// Feed is the format independent feed that the rest of the app works with.
+// RSS, Atom and JSON Feed documents are all mapped onto it.
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Items       []Item
+	Schedule    Schedule
+}
+
+// Item is a single post of a Feed. Published is the zero time when the feed
+// does not give a date or gives one in an unknown format.
+type Item struct {
+	Title       string
+	Link        string
+	Description string
+	Author      string
+	Published   time.Time
+}
+
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title           string       `xml:"title"`
+		Link            string       `xml:"link"`
+		Description     string       `xml:"description"`
+		TTL             string       `xml:"ttl"`
+		SkipHours       RSSSkipHours `xml:"skipHours"`
+		SkipDays        RSSSkipDays  `xml:"skipDays"`
+		UpdatePeriod    string       `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string       `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+		Item            []RSSItem    `xml:"item"`
 	} `xml:"channel"`
 }
 
@@ -22,14 +52,47 @@ type RSSItem struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
 	PubDate     string `xml:"pubDate"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// CacheValidators are the response headers used to ask the server for the
+// feed only when it has changed since the last fetch
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchResult is the outcome of a conditional fetch. when NotModified is
+// true the feed has not changed and Feed is nil.
+type FetchResult struct {
+	Feed        *Feed
+	Validators  CacheValidators
+	NotModified bool
+}
+
+func FetchFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	result, err := FetchFeedConditional(ctx, feedURL, CacheValidators{})
+	if err != nil {
+		return &Feed{}, err
+	}
+
+	return result.Feed, nil
+}
+
+// FetchFeedConditional fetches a feed with If-None-Match and
+// If-Modified-Since set from the validators of the previous fetch. a 304 Not
+// Modified response is a successful fetch with NotModified set.
+func FetchFeedConditional(ctx context.Context, feedURL string, validators CacheValidators) (*FetchResult, error) {
+	return fetchFeed(ctx, http.DefaultClient, feedURL, validators)
+}
+
+func fetchFeed(ctx context.Context, client *http.Client, feedURL string, validators CacheValidators) (*FetchResult, error) {
 	// create a new request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
 	if err != nil {
-		return &RSSFeed{}, fmt.Errorf("failed to create request: %w", err)
+		return &FetchResult{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// apparently, closing the request body causes an error. maybe because
@@ -42,31 +105,219 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	// this is a common practice to identify the program to the server
 	req.Header.Set("User-Agent", "gator")
 
-	// send/process the request
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
 	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	// send/process the request. the deadline of ctx is the time limit.
 	res, err := client.Do(req)
 	if err != nil {
-		return &RSSFeed{}, err
+		return &FetchResult{}, err
 	}
 	defer res.Body.Close()
 
-	// parse response body
-	feed := &RSSFeed{}
-	if err := xml.NewDecoder(res.Body).Decode(feed); err != nil {
-		return &RSSFeed{}, fmt.Errorf("failed to decode feed: %w", err)
+	if res.StatusCode == http.StatusNotModified {
+		// a 304 may leave out the validators, keep the ones we sent
+		return &FetchResult{
+			Validators:  mergeValidators(validators, res.Header),
+			NotModified: true,
+		}, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return &FetchResult{}, &HTTPError{
+			URL:        feedURL,
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			RetryAfter: parseRetryAfter(res, time.Now()),
+		}
+	}
+
+	if res.ContentLength > MaxFeedSize {
+		return &FetchResult{}, &TooLargeError{URL: feedURL, Limit: MaxFeedSize}
+	}
+
+	// read the whole body, the content type or the body itself decides how
+	// it is parsed. read one byte past the limit to tell a body of exactly
+	// MaxFeedSize bytes from a larger one.
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxFeedSize+1))
+	if err != nil {
+		return &FetchResult{}, fmt.Errorf("failed to read feed: %w", err)
+	}
+	if len(body) > MaxFeedSize {
+		return &FetchResult{}, &TooLargeError{URL: feedURL, Limit: MaxFeedSize}
+	}
+
+	// web pages are also sent without a content type or as text/plain, they
+	// must fail the same way so the caller can look for their feeds
+	contentType := res.Header.Get("Content-Type")
+	if (!isFeedContentType(contentType) && !looksLikeFeed(body)) || isHTMLPage(body) {
+		return &FetchResult{}, &ContentTypeError{URL: feedURL, ContentType: contentType}
+	}
+
+	feed, err := ParseFeed(contentType, body)
+	if err != nil {
+		return &FetchResult{}, err
+	}
+
+	return &FetchResult{
+		Feed: feed,
+		Validators: CacheValidators{
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+// mergeValidators returns the validators of a response, falling back to the
+// previous ones for headers the response does not set
+func mergeValidators(previous CacheValidators, header http.Header) CacheValidators {
+	if etag := header.Get("ETag"); etag != "" {
+		previous.ETag = etag
 	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		previous.LastModified = lastModified
+	}
+
+	return previous
+}
+
+// ParseFeed parses an RSS 2.0, Atom 1.0 or JSON Feed 1.1 document into a
+// Feed. contentType is the Content-Type header of the response, if any.
+func ParseFeed(contentType string, body []byte) (*Feed, error) {
+	if isJSONFeed(contentType, body) {
+		jsonFeed := &JSONFeed{}
+		if err := json.Unmarshal(body, jsonFeed); err != nil {
+			return &Feed{}, fmt.Errorf("failed to decode JSON feed: %w", err)
+		}
+		if !strings.HasPrefix(jsonFeed.Version, "https://jsonfeed.org/version/") {
+			return &Feed{}, fmt.Errorf("unsupported JSON feed version: %q", jsonFeed.Version)
+		}
+		return jsonFeed.normalize(), nil
+	}
+
+	root, err := rootElement(body)
+	if err != nil {
+		return &Feed{}, fmt.Errorf("failed to decode feed: %w", err)
+	}
+
+	switch root {
+	case "rss":
+		rssFeed := &RSSFeed{}
+		if err := xml.Unmarshal(body, rssFeed); err != nil {
+			return &Feed{}, fmt.Errorf("failed to decode feed: %w", err)
+		}
+		return rssFeed.normalize(), nil
+	case "feed":
+		atomFeed := &AtomFeed{}
+		if err := xml.Unmarshal(body, atomFeed); err != nil {
+			return &Feed{}, fmt.Errorf("failed to decode feed: %w", err)
+		}
+		return atomFeed.normalize(), nil
+	default:
+		return &Feed{}, fmt.Errorf("unsupported feed format: <%s>", root)
+	}
+}
+
+// isFeedContentType reports whether a content type can hold a feed. servers
+// label feeds with every XML and JSON type, and sometimes with none at all.
+func isFeedContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "text/xml", "application/xml", "application/json", "text/plain", "application/octet-stream":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+json")
+}
+
+// looksLikeFeed reports whether a body is a feed whatever its content type
+// says, for servers that send feeds as text/html
+func looksLikeFeed(body []byte) bool {
+	if isJSONFeed("", body) {
+		return true
+	}
+
+	root, err := rootElement(body)
+	return err == nil && (root == "rss" || root == "feed")
+}
+
+// isHTMLPage reports whether the root element of a body is html. the decoder
+// is not strict, so HTML that is not valid XML is still recognised.
+func isHTMLPage(body []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return strings.EqualFold(start.Name.Local, "html")
+		}
+	}
+}
+
+// rootElement returns the local name of the first element in an XML document
+func rootElement(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func (f *RSSFeed) normalize() *Feed {
+	feed := &Feed{
+		Title:       html.UnescapeString(f.Channel.Title),
+		Link:        html.UnescapeString(f.Channel.Link),
+		Description: html.UnescapeString(f.Channel.Description),
+		Items:       make([]Item, 0, len(f.Channel.Item)),
+		Schedule: newSchedule(
+			f.Channel.TTL,
+			f.Channel.UpdatePeriod,
+			f.Channel.UpdateFrequency,
+			f.Channel.SkipHours,
+			f.Channel.SkipDays,
+		),
+	}
+
+	for _, field := range f.Channel.Item {
+		// an unknown date leaves Published as the zero time
+		published, _ := ParseDate(html.UnescapeString(field.PubDate))
+
+		// author is meant to be an email address, most feeds use the
+		// Dublin Core creator for the name instead
+		author := field.Creator
+		if author == "" {
+			author = field.Author
+		}
 
-	feed.Channel.Link = html.UnescapeString(feed.Channel.Link)
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
-	for i, field := range feed.Channel.Item {
-		feed.Channel.Item[i].Description = html.UnescapeString(field.Description)
-		feed.Channel.Item[i].Link = html.UnescapeString(field.Link)
-		feed.Channel.Item[i].PubDate = html.UnescapeString(field.PubDate)
-		feed.Channel.Item[i].Title = html.UnescapeString(field.Title)
+		feed.Items = append(feed.Items, Item{
+			Title:       html.UnescapeString(field.Title),
+			Link:        html.UnescapeString(field.Link),
+			Description: html.UnescapeString(field.Description),
+			Author:      html.UnescapeString(author),
+			Published:   published,
+		})
 	}
 
-	return feed, nil
+	return feed
 }
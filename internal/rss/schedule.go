@@ -0,0 +1,106 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is how often the publisher wants a feed to be fetched
+type Schedule struct {
+	// MinInterval is the shortest time between two fetches
+	MinInterval time.Duration
+	// SkipHours has bit n set when the feed should not be fetched during
+	// hour n, in GMT
+	SkipHours uint32
+	// SkipDays has bit n set when the feed should not be fetched on
+	// time.Weekday(n), in GMT
+	SkipDays uint8
+}
+
+// RSSSkipHours and RSSSkipDays are the <skipHours> and <skipDays> elements
+// of an RSS channel. the hours are read as text so that a malformed one
+// doesn't reject the whole feed.
+type RSSSkipHours struct {
+	Hour []string `xml:"hour"`
+}
+
+type RSSSkipDays struct {
+	Day []string `xml:"day"`
+}
+
+// syndicationPeriods are the values of the syndication module's
+// updatePeriod element
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// parseHint reads a number from a scheduling element, ok is false when the
+// element is missing or malformed
+func parseHint(value string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// syndicationInterval returns the interval asked for by sy:updatePeriod and
+// sy:updateFrequency, which is the number of updates per period
+func syndicationInterval(period string, frequency string) time.Duration {
+	duration, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(period))]
+	if !ok {
+		return 0
+	}
+	n, ok := parseHint(frequency)
+	if !ok || n <= 0 {
+		n = 1
+	}
+
+	return duration / time.Duration(n)
+}
+
+// newSchedule builds the schedule from the text of the channel's hints,
+// values that don't parse are ignored
+func newSchedule(ttl string, updatePeriod string, updateFrequency string, skipHours RSSSkipHours, skipDays RSSSkipDays) Schedule {
+	schedule := Schedule{
+		MinInterval: syndicationInterval(updatePeriod, updateFrequency),
+	}
+	if minutes, ok := parseHint(ttl); ok && minutes > 0 {
+		schedule.MinInterval = max(schedule.MinInterval, time.Duration(minutes)*time.Minute)
+	}
+
+	// the spec numbers hours 0-23, some feeds use 24 for midnight
+	for _, value := range skipHours.Hour {
+		if hour, ok := parseHint(value); ok && hour >= 0 && hour <= 24 {
+			schedule.SkipHours |= 1 << (hour % 24)
+		}
+	}
+	for _, day := range skipDays.Day {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				schedule.SkipDays |= 1 << weekday
+			}
+		}
+	}
+
+	return schedule
+}
+
+// NextFetch returns the earliest time after a fetch at t when the feed may
+// be fetched again. a schedule that skips every hour of the week is ignored.
+func (s Schedule) NextFetch(t time.Time) time.Time {
+	next := t.Add(s.MinInterval).UTC()
+	for range 7 * 24 {
+		if s.SkipHours&(1<<next.Hour()) == 0 && s.SkipDays&(1<<next.Weekday()) == 0 {
+			return next
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return t.Add(s.MinInterval).UTC()
+}
@@ -0,0 +1,87 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSchedule(t *testing.T) {
+	// a Tuesday, in GMT
+	fetched := time.Date(2003, time.June, 10, 4, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		ttl       string
+		period    string
+		frequency string
+		hours     []string
+		days      []string
+		want      string
+	}{
+		{name: "no hints", want: "2003-06-10T04:00:00Z"},
+		{name: "ttl", ttl: "60", want: "2003-06-10T05:00:00Z"},
+		{name: "ttl with spaces", ttl: " 90\n", want: "2003-06-10T05:30:00Z"},
+		{name: "ttl with unit", ttl: "60 min", want: "2003-06-10T04:00:00Z"},
+		{name: "negative ttl", ttl: "-5", want: "2003-06-10T04:00:00Z"},
+		{name: "update period", period: "daily", want: "2003-06-11T04:00:00Z"},
+		{name: "update frequency", period: "daily", frequency: "4", want: "2003-06-10T10:00:00Z"},
+		{name: "blank update frequency", period: "daily", frequency: " ", want: "2003-06-11T04:00:00Z"},
+		{name: "malformed update frequency", period: "Hourly", frequency: "often", want: "2003-06-10T05:00:00Z"},
+		{name: "unknown update period", period: "fortnightly", frequency: "1", want: "2003-06-10T04:00:00Z"},
+		{name: "longest interval wins", ttl: "30", period: "hourly", want: "2003-06-10T05:00:00Z"},
+		{name: "skip hours", hours: []string{"4", " 5 ", "6"}, want: "2003-06-10T07:00:00Z"},
+		{name: "skip hour 24 is midnight", ttl: "1200", hours: []string{"24"}, want: "2003-06-11T01:00:00Z"},
+		{name: "malformed skip hours", hours: []string{"twelve", "4", "", "25"}, want: "2003-06-10T05:00:00Z"},
+		{name: "skip days", days: []string{"Tuesday", " wednesday "}, want: "2003-06-12T00:00:00Z"},
+		{name: "malformed skip days", days: []string{"Tue", "someday"}, want: "2003-06-10T04:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := time.Parse(time.RFC3339, test.want)
+			if err != nil {
+				t.Fatalf("invalid test date %q: %v", test.want, err)
+			}
+
+			schedule := newSchedule(test.ttl, test.period, test.frequency, RSSSkipHours{Hour: test.hours}, RSSSkipDays{Day: test.days})
+			if got := schedule.NextFetch(fetched); !got.Equal(want) {
+				t.Errorf("NextFetch = %s, want %s", got.Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchEveryHourSkipped(t *testing.T) {
+	fetched := time.Date(2003, time.June, 10, 4, 0, 0, 0, time.UTC)
+	schedule := Schedule{MinInterval: time.Hour, SkipHours: 1<<24 - 1}
+
+	if got, want := schedule.NextFetch(fetched), fetched.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("NextFetch = %s, want %s", got.Format(time.RFC3339), want.Format(time.RFC3339))
+	}
+}
+
+func TestParseFeedMalformedSchedule(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"ttl", `<rss version="2.0"><channel><title>Example</title><ttl>60 min</ttl></channel></rss>`},
+		{"skip hours", `<rss version="2.0"><channel><title>Example</title><skipHours><hour>twelve</hour></skipHours></channel></rss>`},
+		{"rss update frequency", `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel><title>Example</title>` +
+			`<sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency> </sy:updateFrequency></channel></rss>`},
+		{"atom update frequency", `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><title>Example</title>` +
+			`<sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency> </sy:updateFrequency></feed>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed, err := ParseFeed("application/xml", []byte(test.body))
+			if err != nil {
+				t.Fatalf("ParseFeed error = %v", err)
+			}
+			if feed.Title != "Example" {
+				t.Errorf("Title = %q, want %q", feed.Title, "Example")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used to draw the screen
+const (
+	altScreenOn  = "\x1b[?1049h"
+	altScreenOff = "\x1b[?1049l"
+	cursorHide   = "\x1b[?25l"
+	cursorShow   = "\x1b[?25h"
+	clearScreen  = "\x1b[2J"
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+)
+
+// isTerminal reports whether f is a terminal rather than a pipe or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stty runs stty on the terminal and returns its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run stty %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// enableRawMode passes every key to the reader as it is pressed, without
+// echoing it. the returned function restores the previous terminal settings.
+func enableRawMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		stty(saved)
+	}, nil
+}
+
+// terminalSize returns the number of columns and rows of the terminal
+func terminalSize() (width, height int) {
+	size, err := stty("size")
+	if err == nil {
+		if _, err := fmt.Sscan(size, &height, &width); err == nil && width > 0 && height > 0 {
+			return width, height
+		}
+	}
+	return 80, 24
+}
+
+// readKeys sends the keys pressed on the terminal. arrow keys and the other
+// keys that send escape sequences are translated to their names.
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+
+		input := string(buf[:n])
+		for input != "" {
+			key, size := nextKey(input)
+			keys <- key
+			input = input[size:]
+		}
+	}
+}
+
+// escapeKeys maps the escape sequences of special keys to their names
+var escapeKeys = map[string]string{
+	"\x1b[A":  "up",
+	"\x1b[B":  "down",
+	"\x1b[C":  "right",
+	"\x1b[D":  "left",
+	"\x1b[H":  "home",
+	"\x1b[F":  "end",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+	"\x1bOA":  "up",
+	"\x1bOB":  "down",
+	"\x1bOC":  "right",
+	"\x1bOD":  "left",
+}
+
+// nextKey returns the name of the first key in input and its length in bytes
+func nextKey(input string) (string, int) {
+	for sequence, name := range escapeKeys {
+		if strings.HasPrefix(input, sequence) {
+			return name, len(sequence)
+		}
+	}
+
+	switch input[0] {
+	case '\x1b':
+		return "esc", 1
+	case '\r', '\n':
+		return "enter", 1
+	case '\t':
+		return "tab", 1
+	case 0x03:
+		return "ctrl-c", 1
+	case 0x04:
+		return "ctrl-d", 1
+	case 0x15:
+		return "ctrl-u", 1
+	}
+
+	_, size := utf8.DecodeRuneInString(input)
+	return input[:size], size
+}
+
+// openBrowser opens url in $BROWSER, or in the default browser of the system
+// when it is not set. like other tools, $BROWSER may list several browsers
+// separated by colons and use %s for the URL.
+func openBrowser(url string) error {
+	browser := os.Getenv("BROWSER")
+	if browser == "" {
+		switch runtime.GOOS {
+		case "darwin":
+			browser = "open"
+		case "windows":
+			browser = "rundll32 url.dll,FileProtocolHandler"
+		default:
+			browser = "xdg-open"
+		}
+	}
+
+	browser, _, _ = strings.Cut(browser, ":")
+	args := strings.Fields(browser)
+	if len(args) == 0 {
+		return fmt.Errorf("$BROWSER is empty.")
+	}
+	if strings.Contains(browser, "%s") {
+		for i := range args {
+			args[i] = strings.ReplaceAll(args[i], "%s", url)
+		}
+	} else {
+		args = append(args, url)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	// reap the browser process once it exits
+	go cmd.Wait()
+
+	return nil
+}
@@ -0,0 +1,548 @@
+// Package tui is a full-screen reader for the posts of the feeds a user
+// follows. it draws with ANSI escape sequences and puts the terminal in raw
+// mode with stty, so it runs on any Unix terminal.
+package tui
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/johndosdos/blog_aggregator/internal/database"
+)
+
+// postsLimit is the number of posts loaded for the selected feed
+const postsLimit = 500
+
+const helpText = "j/k move  h/l switch pane  o open  m read/unread  s star  u unread only  J/K scroll  r refresh  q quit"
+
+type pane int
+
+const (
+	feedsPane pane = iota
+	postsPane
+)
+
+// feedEntry is a line of the feed list. the first entry shows the posts of
+// every feed and has no ID.
+type feedEntry struct {
+	ID   uuid.NullUUID
+	Name string
+}
+
+// reader holds the state of the screen
+type reader struct {
+	db   *database.Queries
+	user database.User
+
+	feeds      []feedEntry
+	feedCursor int
+	feedTop    int
+
+	posts      []database.GetPostsForUserRow
+	postCursor int
+	postTop    int
+
+	previewTop int
+	focus      pane
+	unreadOnly bool
+	status     string
+
+	// the size of the terminal, read again only when it is resized since
+	// reading it runs stty
+	width, height int
+}
+
+// Run shows the reader until the user quits
+func Run(db *database.Queries, user database.User) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("the reader needs a terminal.")
+	}
+
+	r := &reader{db: db, user: user}
+	if err := r.loadFeeds(); err != nil {
+		return err
+	}
+	if err := r.loadPosts(); err != nil {
+		return err
+	}
+
+	restore, err := enableRawMode()
+	if err != nil {
+		return err
+	}
+	fmt.Print(altScreenOn + cursorHide + clearScreen)
+	defer func() {
+		fmt.Print(styleReset + cursorShow + altScreenOff)
+		restore()
+	}()
+
+	keys := make(chan string)
+	go readKeys(keys)
+
+	// redraw when the terminal is resized
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	defer signal.Stop(resized)
+
+	r.width, r.height = terminalSize()
+	r.draw()
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !r.handleKey(key) {
+				return nil
+			}
+		case <-resized:
+			r.width, r.height = terminalSize()
+		}
+		r.draw()
+	}
+}
+
+func (r *reader) loadFeeds() error {
+	follows, err := r.db.GetFeedFollowsForUser(context.Background(), r.user.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	r.feeds = []feedEntry{{Name: "All feeds"}}
+	for _, follow := range follows {
+		r.feeds = append(r.feeds, feedEntry{
+			ID:   uuid.NullUUID{UUID: follow.FeedID, Valid: true},
+			Name: follow.Name_2,
+		})
+	}
+	r.feedCursor = min(r.feedCursor, len(r.feeds)-1)
+
+	return nil
+}
+
+// loadPosts loads the posts of the selected feed, keeping the selected post
+// when it is still in the list
+func (r *reader) loadPosts() error {
+	selected := uuid.Nil
+	if post, ok := r.selectedPost(); ok {
+		selected = post.ID
+	}
+
+	posts, err := r.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+		UserID:     r.user.ID,
+		UnreadOnly: r.unreadOnly,
+		FeedID:     r.feeds[r.feedCursor].ID,
+		PageLimit:  postsLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	r.posts = posts
+	r.postCursor = 0
+	for i, post := range posts {
+		if post.ID == selected {
+			r.postCursor = i
+		}
+	}
+	r.previewTop = 0
+
+	return nil
+}
+
+func (r *reader) selectedPost() (database.GetPostsForUserRow, bool) {
+	if r.postCursor >= len(r.posts) {
+		return database.GetPostsForUserRow{}, false
+	}
+	return r.posts[r.postCursor], true
+}
+
+// handleKey runs the action bound to a key. it returns false to quit.
+func (r *reader) handleKey(key string) bool {
+	r.status = ""
+
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "j", "down":
+		r.move(1)
+	case "k", "up":
+		r.move(-1)
+	case "ctrl-d", "pgdown":
+		r.move(10)
+	case "ctrl-u", "pgup":
+		r.move(-10)
+	case "g", "home":
+		r.move(-len(r.feeds) - len(r.posts))
+	case "G", "end":
+		r.move(len(r.feeds) + len(r.posts))
+	case "h", "left":
+		r.focus = feedsPane
+	case "l", "right", "enter":
+		if r.focus == feedsPane {
+			r.focus = postsPane
+		} else if key == "enter" {
+			r.open()
+		}
+	case "tab":
+		r.focus = 1 - r.focus
+	case "J":
+		r.previewTop++
+	case "K":
+		r.previewTop = max(r.previewTop-1, 0)
+	case "o":
+		r.open()
+	case "m":
+		r.toggleRead()
+	case "s":
+		r.toggleStar()
+	case "u":
+		r.unreadOnly = !r.unreadOnly
+		r.reload()
+	case "r":
+		r.reload()
+		if r.status == "" {
+			r.status = "refreshed."
+		}
+	}
+
+	return true
+}
+
+// move moves the cursor of the focused pane by delta lines
+func (r *reader) move(delta int) {
+	if r.focus == feedsPane {
+		cursor := clamp(r.feedCursor+delta, 0, len(r.feeds)-1)
+		if cursor != r.feedCursor {
+			r.feedCursor = cursor
+			r.postCursor = 0
+			r.postTop = 0
+			if err := r.loadPosts(); err != nil {
+				r.status = err.Error()
+			}
+		}
+		return
+	}
+
+	if len(r.posts) == 0 {
+		return
+	}
+	cursor := clamp(r.postCursor+delta, 0, len(r.posts)-1)
+	if cursor != r.postCursor {
+		r.postCursor = cursor
+		r.previewTop = 0
+	}
+}
+
+// reload loads the feeds and posts again, picking up the posts saved by agg
+// since the reader started
+func (r *reader) reload() {
+	if err := r.loadFeeds(); err != nil {
+		r.status = err.Error()
+		return
+	}
+	if err := r.loadPosts(); err != nil {
+		r.status = err.Error()
+	}
+}
+
+// open opens the selected post in the browser and marks it as read
+func (r *reader) open() {
+	post, ok := r.selectedPost()
+	if !ok {
+		return
+	}
+	if err := openBrowser(post.Url); err != nil {
+		r.status = err.Error()
+		return
+	}
+	if !post.ReadAt.Valid {
+		r.toggleRead()
+	}
+	r.status = "opened " + post.Url
+}
+
+// toggleRead marks the selected post as read, or as unread when it is read.
+// the post stays in the list until the next refresh, even with unread only.
+func (r *reader) toggleRead() {
+	post, ok := r.selectedPost()
+	if !ok {
+		return
+	}
+
+	var err error
+	if post.ReadAt.Valid {
+		err = r.db.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+			UserID: r.user.ID,
+			PostID: post.ID,
+		})
+		post.ReadAt = sql.NullTime{}
+	} else {
+		now := time.Now().UTC()
+		err = r.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: r.user.ID,
+			PostID: post.ID,
+			ReadAt: sql.NullTime{Time: now, Valid: true},
+		})
+		post.ReadAt = sql.NullTime{Time: now, Valid: true}
+	}
+	if err != nil {
+		r.status = fmt.Sprintf("failed to update post: %v", err)
+		return
+	}
+
+	r.posts[r.postCursor] = post
+}
+
+// toggleStar stars the selected post, or removes its star
+func (r *reader) toggleStar() {
+	post, ok := r.selectedPost()
+	if !ok {
+		return
+	}
+
+	var err error
+	if post.StarredAt.Valid {
+		err = r.db.UnstarPost(context.Background(), database.UnstarPostParams{
+			UserID: r.user.ID,
+			PostID: post.ID,
+		})
+		post.StarredAt = sql.NullTime{}
+	} else {
+		now := time.Now().UTC()
+		err = r.db.StarPost(context.Background(), database.StarPostParams{
+			UserID:    r.user.ID,
+			PostID:    post.ID,
+			StarredAt: sql.NullTime{Time: now, Valid: true},
+		})
+		post.StarredAt = sql.NullTime{Time: now, Valid: true}
+	}
+	if err != nil {
+		r.status = fmt.Sprintf("failed to update post: %v", err)
+		return
+	}
+
+	r.posts[r.postCursor] = post
+}
+
+// draw redraws the whole screen: the feeds on the left, the posts on the
+// top right, the preview of the selected post below them and a status line
+func (r *reader) draw() {
+	width, height := r.width, r.height
+	contentHeight := max(height-1, 3)
+	feedsWidth := clamp(width/4, min(20, width/2), width/2)
+	postsWidth := max(width-feedsWidth-1, 1)
+	postsHeight := max(contentHeight/2, 2)
+	previewHeight := max(contentHeight-postsHeight-1, 0)
+
+	left := r.feedLines(feedsWidth, contentHeight)
+	right := r.postLines(postsWidth, postsHeight)
+	right = append(right, styleDim+strings.Repeat("─", postsWidth)+styleReset)
+	right = append(right, r.previewLines(postsWidth, previewHeight)...)
+
+	// every row is padded to the full width, so the screen does not need to
+	// be cleared first, which would flicker
+	var screen strings.Builder
+	for row := range contentHeight {
+		fmt.Fprintf(&screen, "\x1b[%d;1H", row+1)
+		screen.WriteString(lineAt(left, row, feedsWidth))
+		screen.WriteString(styleDim + "│" + styleReset)
+		screen.WriteString(lineAt(right, row, postsWidth))
+	}
+
+	status := r.status
+	if status == "" {
+		status = helpText
+	}
+	fmt.Fprintf(&screen, "\x1b[%d;1H%s%s%s", height, styleReverse, lineAt([]string{fit(status, width)}, 0, width), styleReset)
+
+	fmt.Print(screen.String())
+}
+
+func (r *reader) feedLines(width, height int) []string {
+	title := "Feeds"
+	if r.unreadOnly {
+		title += " (unread)"
+	}
+	lines := []string{styleBold + fit(title, width) + styleReset}
+
+	r.feedTop = scrollTop(r.feedTop, r.feedCursor, height-1)
+	for i := r.feedTop; i < len(r.feeds) && len(lines) < height; i++ {
+		lines = append(lines, r.cursorLine(fit(" "+r.feeds[i].Name, width), feedsPane, i == r.feedCursor))
+	}
+
+	return lines
+}
+
+func (r *reader) postLines(width, height int) []string {
+	lines := []string{styleBold + fit(fmt.Sprintf("Posts (%d)", len(r.posts)), width) + styleReset}
+	if len(r.posts) == 0 {
+		return append(lines, fit(" no posts. run agg to fetch the feeds.", width))
+	}
+
+	r.postTop = scrollTop(r.postTop, r.postCursor, height-1)
+	for i := r.postTop; i < len(r.posts) && len(lines) < height; i++ {
+		post := r.posts[i]
+		marks := " "
+		if !post.ReadAt.Valid {
+			marks = "●"
+		}
+		if post.StarredAt.Valid {
+			marks += "★"
+		} else {
+			marks += " "
+		}
+		date := "          "
+		if post.PublishedAt.Valid {
+			date = post.PublishedAt.Time.Local().Format(time.DateOnly)
+		}
+
+		line := fit(fmt.Sprintf(" %s %s  %s", marks, date, post.Title), width)
+		if !post.ReadAt.Valid {
+			line = styleBold + line + styleReset
+		}
+		lines = append(lines, r.cursorLine(line, postsPane, i == r.postCursor))
+	}
+
+	return lines
+}
+
+func (r *reader) previewLines(width, height int) []string {
+	post, ok := r.selectedPost()
+	if !ok || height <= 0 {
+		return nil
+	}
+
+	date := "unknown date"
+	if post.PublishedAt.Valid {
+		date = post.PublishedAt.Time.Local().Format(time.DateTime)
+	}
+
+	lines := []string{}
+	lines = append(lines, wrap(post.Title, width)...)
+	lines = append(lines, wrap(post.FeedName+" | "+date, width)...)
+	lines = append(lines, wrap(post.Url, width)...)
+	lines = append(lines, "")
+	lines = append(lines, wrap(htmlToText(post.Description.String), width)...)
+
+	// J scrolls the preview down, but not past its last line
+	r.previewTop = clamp(r.previewTop, 0, max(len(lines)-height, 0))
+	lines = lines[r.previewTop:]
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	return lines
+}
+
+// cursorLine highlights the line under the cursor, in reverse video when its
+// pane has the focus
+func (r *reader) cursorLine(line string, p pane, selected bool) string {
+	switch {
+	case !selected:
+		return line
+	case r.focus == p:
+		return styleReverse + line + styleReset
+	default:
+		return styleBold + line + styleReset
+	}
+}
+
+// lineAt returns the line of a pane at row, padded to the width of the pane
+func lineAt(lines []string, row, width int) string {
+	if row < len(lines) {
+		// the lines are already cut to the width, padding is added after the
+		// escape sequences so they don't count
+		visible := len([]rune(stripEscapes(lines[row])))
+		return lines[row] + strings.Repeat(" ", max(width-visible, 0))
+	}
+	return strings.Repeat(" ", width)
+}
+
+var escapePattern = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+func stripEscapes(s string) string {
+	return escapePattern.ReplaceAllString(s, "")
+}
+
+// fit cuts s to width runes, replacing control characters so text from a
+// feed cannot move the cursor
+func fit(s string, width int) string {
+	runes := []rune(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s))
+
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:max(width, 0)])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes)
+}
+
+// wrap breaks text into lines of at most width runes at spaces
+func wrap(text string, width int) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, fit(line, width))
+				line = word
+			}
+		}
+		lines = append(lines, fit(line, width))
+	}
+	return lines
+}
+
+var (
+	blockTagPattern = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/h[1-6]|/blockquote)\b[^>]*>`)
+	tagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankPattern    = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// htmlToText turns the HTML of a post description into plain text, keeping
+// paragraphs apart
+func htmlToText(s string) string {
+	s = blockTagPattern.ReplaceAllString(s, "\n")
+	s = tagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankPattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// scrollTop returns the first visible line of a list so the cursor stays in
+// view
+func scrollTop(top, cursor, height int) int {
+	if height <= 0 {
+		return cursor
+	}
+	if cursor < top {
+		return cursor
+	}
+	if cursor >= top+height {
+		return cursor - height + 1
+	}
+	return top
+}
+
+func clamp(n, low, high int) int {
+	return max(low, min(n, high))
+}
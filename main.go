@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 
@@ -27,11 +28,11 @@ func main() {
 	}
 	dbQueries := database.New(db)
 
-	if len(os.Args) < 2 {
-		fmt.Println("missing argument. e.g. <command> [arguments]")
-		os.Exit(1)
-	}
+	// with no command, list the commands
 	args := os.Args
+	if len(args) < 2 {
+		args = []string{args[0], "help"}
+	}
 
 	cmd := commands.Command{
 		// when using os.Args, we need to start at index 1 because index 0 is the program name
@@ -48,36 +49,18 @@ func main() {
 	   		Args: []string{"jane"},
 	   	} */
 
-	state := &commands.State{Config: &newConfig, DB: dbQueries}
+	state := &commands.State{Config: &newConfig, DB: dbQueries, DBConn: db}
 
-	handlerMap := make(map[string]func(*commands.State, commands.Command) error)
-	cmds := commands.Commands{Handlers: handlerMap}
-
-	switch cmd.Name {
-	case "login":
-		cmds.Register(cmd.Name, commands.HandlerLogin)
-	case "register":
-		cmds.Register(cmd.Name, commands.HandlerRegister)
-	case "reset":
-		cmds.Register(cmd.Name, commands.HandlerReset)
-	case "users":
-		cmds.Register(cmd.Name, commands.HandlerUsers)
-	case "agg":
-		cmds.Register(cmd.Name, commands.HandlerAgg)
-	case "feeds":
-		cmds.Register(cmd.Name, commands.HandlerFeeds)
-	case "addfeed":
-		cmds.Register(cmd.Name, commands.MiddlewareLoggedIn(commands.HandlerAddFeed))
-	case "follow":
-		cmds.Register(cmd.Name, commands.MiddlewareLoggedIn(commands.HandlerFollow))
-	case "following":
-		cmds.Register(cmd.Name, commands.MiddlewareLoggedIn(commands.HandlerFollowing))
-	case "unfollow":
-		cmds.Register(cmd.Name, commands.MiddlewareLoggedIn(commands.HandlerUnfollow))
-	}
+	cmds := commands.NewCommands()
 
 	if err := cmds.Run(state, cmd); err != nil {
 		fmt.Println(err)
+
+		// misuse of a command exits with 2, like the flag package
+		usageErr := &commands.UsageError{}
+		if errors.As(err, &usageErr) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 
@@ -18,12 +18,25 @@ INNER JOIN feeds ON feeds.id = inserted_feed_follow.feed_id;
 SELECT
     feed_follows.*,
     users.name,
-    feeds.name
+    feeds.name,
+    feeds.url
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 WHERE users.name = $1;
 
+-- name: GetFeedFollowsPageForUser :many
+SELECT
+    feed_follows.feed_id,
+    feed_follows.created_at,
+    feeds.name AS feed_name,
+    feeds.url
+FROM feed_follows
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+ORDER BY feed_follows.created_at, feed_follows.id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
 -- name: DeleteUsersFeedFollows :exec
 DELETE FROM feed_follows;
 
@@ -33,5 +46,9 @@ WHERE
     feed_follows.user_id = $1 AND feed_follows.feed_id = (
         SELECT id 
         FROM feeds
-        WHERE url = $2
-    );
\ No newline at end of file
+        WHERE url_key = $2
+    );
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
@@ -1,6 +1,6 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (id, created_at, updated_at, name, url, url_key, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetFeeds :many
@@ -10,8 +10,89 @@ SELECT
 FROM feeds
 JOIN users ON feeds.user_id = users.id;
 
--- name: GetFeedByUrl :one
-SELECT * FROM feeds WHERE url = $1;
+-- name: GetFeedsPage :many
+SELECT
+    feeds.id,
+    feeds.created_at,
+    feeds.name,
+    feeds.url,
+    feeds.last_fetched_at,
+    users.name AS username
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+ORDER BY feeds.created_at, feeds.id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: GetFeedByUrlKey :one
+SELECT * FROM feeds WHERE url_key = $1;
 
 -- name: DeleteFeeds :exec
-DELETE FROM feeds;
\ No newline at end of file
+DELETE FROM feeds;
+
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET
+    last_fetched_at = $2,
+    updated_at = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    next_attempt_at = $3
+WHERE id = $1;
+
+-- name: MarkFeedFailed :exec
+UPDATE feeds
+SET
+    last_error = $2,
+    consecutive_failures = $3,
+    next_attempt_at = $4,
+    disabled_at = $5
+WHERE id = $1;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET
+    updated_at = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    next_attempt_at = NULL,
+    disabled_at = NULL
+WHERE id = $1;
+
+-- name: GetNextFeedsToFetch :many
+SELECT * FROM feeds
+WHERE disabled_at IS NULL
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT $1;
+
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET updated_at = $1, next_attempt_at = $3
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE
+        disabled_at IS NULL
+        AND (next_attempt_at IS NULL OR next_attempt_at <= $1)
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
+WHERE id = $1;
+
+-- name: GetFeedsWithErrors :many
+SELECT
+    feeds.*,
+    users.name AS username
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.consecutive_failures > 0 OR feeds.disabled_at IS NOT NULL
+ORDER BY feeds.disabled_at DESC NULLS LAST, feeds.consecutive_failures DESC;
+
+-- name: SetFeedRefreshSchedule :exec
+UPDATE feeds
+SET min_refresh_interval = $2, skip_hours = $3, skip_days = $4
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,67 @@
+-- name: UpsertPost :exec
+INSERT INTO posts (id, created_at, updated_at, title, url, url_key, description, published_at, feed_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (url_key) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    url = EXCLUDED.url,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at;
+
+-- name: GetPostForUser :one
+SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE posts.id = sqlc.arg(id) AND feed_follows.user_id = sqlc.arg(user_id);
+
+-- name: GetPostsForUser :many
+SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    feeds.name AS feed_name,
+    user_post_state.read_at,
+    user_post_state.starred_at
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN user_post_state ON user_post_state.post_id = posts.id
+    AND user_post_state.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(unread_only)::BOOLEAN OR user_post_state.read_at IS NULL)
+AND (NOT sqlc.arg(starred_only)::BOOLEAN OR user_post_state.starred_at IS NOT NULL)
+AND (sqlc.narg(feed_id)::UUID IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: SearchPostsForUser :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank(posts.search_vector, to_tsquery('english', sqlc.arg(query))) AS rank
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.search_vector @@ to_tsquery('english', sqlc.arg(query))
+AND (sqlc.narg(feed_id)::UUID IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+AND (sqlc.narg(since)::TIMESTAMP IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
@@ -0,0 +1,33 @@
+-- name: MarkAllPostsRead :execrows
+INSERT INTO user_post_state (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::TIMESTAMP
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_id)::UUID IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+AND (sqlc.narg(before)::TIMESTAMP IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(before))
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = EXCLUDED.read_at
+WHERE user_post_state.read_at IS NULL;
+
+-- name: MarkPostRead :exec
+INSERT INTO user_post_state (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(user_post_state.read_at, EXCLUDED.read_at);
+
+-- name: MarkPostUnread :exec
+UPDATE user_post_state
+SET read_at = NULL
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: StarPost :exec
+INSERT INTO user_post_state (user_id, post_id, starred_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred_at = COALESCE(user_post_state.starred_at, EXCLUDED.starred_at);
+
+-- name: UnstarPost :exec
+UPDATE user_post_state
+SET starred_at = NULL
+WHERE user_id = $1 AND post_id = $2;
\ No newline at end of file
@@ -1,14 +1,28 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
-VALUES ($1, $2, $3, $4)
+INSERT INTO users (id, created_at, updated_at, name, api_key_hash)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users
 WHERE name = $1;
 
+-- name: GetUserByApiKeyHash :one
+SELECT * FROM users
+WHERE api_key_hash = $1;
+
 -- name: GetUsers :many
 SELECT * FROM users;
 
+-- name: GetUsersPage :many
+SELECT id, created_at, name FROM users
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: SetUserApiKeyHash :exec
+UPDATE users
+SET api_key_hash = $2, updated_at = $3
+WHERE id = $1;
+
 -- name: DeleteUsers :exec
 DELETE FROM users;
\ No newline at end of file
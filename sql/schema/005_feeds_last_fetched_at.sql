@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN last_fetched_at TIMESTAMP;
+CREATE INDEX feeds_last_fetched_at_idx ON feeds (last_fetched_at NULLS FIRST);
+
+-- +goose Down
+DROP INDEX feeds_last_fetched_at_idx;
+ALTER TABLE feeds DROP COLUMN last_fetched_at;
\ No newline at end of file
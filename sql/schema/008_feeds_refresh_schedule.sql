@@ -0,0 +1,12 @@
+-- +goose Up
+-- min_refresh_interval is in seconds. skip_hours and skip_days are bit sets,
+-- bit n of skip_hours is hour n in GMT and bit n of skip_days is day n of the
+-- week starting on Sunday.
+ALTER TABLE feeds ADD COLUMN min_refresh_interval INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN skip_hours INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN skip_days INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN skip_days;
+ALTER TABLE feeds DROP COLUMN skip_hours;
+ALTER TABLE feeds DROP COLUMN min_refresh_interval;
\ No newline at end of file
@@ -0,0 +1,95 @@
+-- +goose Up
+-- canonical_url mirrors canonical.URL in internal/canonical: lowercase the
+-- scheme and host, drop default ports, fragments, trailing slashes and
+-- tracking parameters, and sort the query parameters. the user info, path
+-- and query are otherwise kept as they are written. the function is kept
+-- for the migrations that add keys to other tables.
+-- +goose StatementBegin
+CREATE FUNCTION canonical_url(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+    scheme TEXT;
+    rest TEXT;
+    authority TEXT;
+    userinfo TEXT;
+    host TEXT;
+    path TEXT;
+    query TEXT;
+BEGIN
+    scheme := lower(substring(raw FROM '^([a-zA-Z][a-zA-Z0-9+.-]*)://'));
+    IF scheme IS NULL THEN
+        RETURN raw;
+    END IF;
+
+    rest := regexp_replace(substring(raw FROM '://(.*)$'), '#.*$', '');
+    authority := substring(rest FROM '^[^/?]*');
+    userinfo := coalesce(substring(authority FROM '^(.*@)'), '');
+    host := lower(substring(authority FROM length(userinfo) + 1));
+    IF (scheme = 'http' AND host LIKE '%:80') OR (scheme = 'https' AND host LIKE '%:443') THEN
+        host := regexp_replace(host, ':[0-9]+$', '');
+    END IF;
+    path := regexp_replace(substring(rest FROM '^[^/?]*([^?]*)'), '/+$', '');
+    query := coalesce(substring(rest FROM '\?(.*)$'), '');
+
+    SELECT coalesce(string_agg(param, '&' ORDER BY param COLLATE "C"), '')
+    INTO query
+    FROM regexp_split_to_table(query, '&') AS param
+    WHERE param <> ''
+        AND lower(split_part(param, '=', 1)) !~ '^utm_'
+        AND lower(split_part(param, '=', 1)) NOT IN (
+            'fbclid', 'gclid', 'dclid', 'msclkid', 'mc_cid', 'mc_eid', 'igshid', 'yclid', '_hsenc', '_hsmi'
+        );
+
+    IF query <> '' THEN
+        RETURN scheme || '://' || userinfo || host || path || '?' || query;
+    END IF;
+    RETURN scheme || '://' || userinfo || host || path;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- feeds are identified by their canonical URL without the scheme, so the
+-- http and https URLs of a feed are the same feed. the url column keeps the
+-- URL that was fetched, its canonical form may not work for every server.
+ALTER TABLE feeds ADD COLUMN url_key TEXT;
+UPDATE feeds SET url_key = regexp_replace(canonical_url(url), '^[a-z][a-z0-9+.-]*://', '');
+
+-- merge duplicate feeds into the oldest feed with the same key
+CREATE TEMPORARY TABLE feed_merges AS
+SELECT
+    id AS duplicate_id,
+    first_value(id) OVER (PARTITION BY url_key ORDER BY created_at, id) AS keep_id
+FROM feeds;
+DELETE FROM feed_merges WHERE duplicate_id = keep_id;
+
+-- move one follow per user to the kept feed, unless the user already
+-- follows it. the other follows are deleted with the duplicate feeds.
+UPDATE feed_follows
+SET feed_id = feed_merges.keep_id
+FROM feed_merges
+WHERE
+    feed_follows.feed_id = feed_merges.duplicate_id
+    AND feed_follows.id IN (
+        SELECT DISTINCT ON (ff.user_id, fm.keep_id) ff.id
+        FROM feed_follows ff
+        INNER JOIN feed_merges fm ON fm.duplicate_id = ff.feed_id
+        ORDER BY ff.user_id, fm.keep_id, ff.created_at
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM feed_follows kept
+        WHERE kept.user_id = feed_follows.user_id AND kept.feed_id = feed_merges.keep_id
+    );
+
+UPDATE posts
+SET feed_id = feed_merges.keep_id
+FROM feed_merges
+WHERE posts.feed_id = feed_merges.duplicate_id;
+
+DELETE FROM feeds WHERE id IN (SELECT duplicate_id FROM feed_merges);
+DROP TABLE feed_merges;
+
+ALTER TABLE feeds ALTER COLUMN url_key SET NOT NULL;
+ALTER TABLE feeds ADD CONSTRAINT feeds_url_key_key UNIQUE (url_key);
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN url_key;
+DROP FUNCTION canonical_url(TEXT);
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE user_post_state (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    read_at TIMESTAMP,
+    starred_at TIMESTAMP,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+-- starred posts are looked up per user
+CREATE INDEX user_post_state_starred_idx ON user_post_state (user_id) WHERE starred_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE user_post_state;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+-- only the SHA-256 hash of the key is stored. users created before keys
+-- existed have none until they run rotate-key.
+ALTER TABLE users ADD COLUMN api_key_hash TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN api_key_hash;
\ No newline at end of file
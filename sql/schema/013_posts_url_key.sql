@@ -0,0 +1,25 @@
+-- +goose Up
+-- posts are identified by their canonical URL without the scheme, like the
+-- feeds. the url column keeps the link of the item as the feed wrote it.
+ALTER TABLE posts ADD COLUMN url_key TEXT;
+UPDATE posts SET url_key = regexp_replace(canonical_url(url), '^[a-z][a-z0-9+.-]*://', '');
+
+-- keep the oldest post with the same key
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT
+            id,
+            row_number() OVER (PARTITION BY url_key ORDER BY created_at, id) AS n
+        FROM posts
+    ) numbered
+    WHERE n > 1
+);
+
+ALTER TABLE posts ALTER COLUMN url_key SET NOT NULL;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key_key UNIQUE (url_key);
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+
+-- +goose Down
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts DROP COLUMN url_key;
\ No newline at end of file